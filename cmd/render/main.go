@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/robkau/coordinate_supplier"
+	"github.com/robkau/go-raytrace/cmd/scene_browser/scenes"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/robkau/go-raytrace/lib/view"
+	"github.com/robkau/go-raytrace/lib/view/canvas"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// options are what the flags ask run to render
+type options struct {
+	scene       string
+	cameraIndex int
+	width       int
+	height      int
+	fov         float64
+	bounces     int
+	workers     int
+	bvh         string
+	out         string
+	// empty picks the format from the out extension
+	format   string
+	pipeline canvas.Pipeline
+	sampling view.Sampling
+	// nil keeps the lens of the scene camera
+	lens       *view.Lens
+	projection string
+	integrator view.Integrator
+	// nil renders a single image
+	rig *view.StereoRig
+	// empty keeps the environment of the scene
+	environment        string
+	environmentSamples int
+	glossy             int
+	// empty skips the heatmap
+	heatmap string
+}
+
+// render one scene without a window and write the image to disk.
+// scenes load their data files relative to the working directory, so run this from the repository root.
+func main() {
+	var o options
+	flag.StringVar(&o.scene, "scene", "", "scene to render, one of: "+strings.Join(scenes.Names(), ", "))
+	flag.IntVar(&o.cameraIndex, "camera", 0, "index of the scene camera location to render from")
+	flag.IntVar(&o.width, "width", 1080, "image width in pixels")
+	flag.IntVar(&o.height, "height", 1080, "image height in pixels")
+	flag.Float64Var(&o.fov, "fov", 0.45, "camera field of view in radians")
+	flag.IntVar(&o.bounces, "bounces", 3, "ray bounces for reflection and refraction, or path length for the path tracer")
+	integratorName := flag.String("integrator", "whitted", "integrator: whitted, or path for path tracing with global illumination")
+	flag.IntVar(&o.workers, "workers", runtime.NumCPU(), "render goroutines")
+	flag.StringVar(&o.bvh, "bvh", shapes.BVHMidpoint.String(), "bvh builder used when scenes are divided: midpoint or sah")
+	flag.StringVar(&o.out, "out", "render.png", "output file, .png, .jpg, .ppm, .hdr or .pfm")
+	flag.StringVar(&o.format, "format", "", "output format instead of the one picked from the file extension: png, jpeg, ppm, ppm-ascii, hdr or pfm")
+	spp := flag.Int("spp", 1, "samples per pixel")
+	pattern := flag.String("pattern", view.SampleGrid.String(), "sample pattern within each pixel: grid, jittered, stratified or adaptive")
+	threshold := flag.Float64("threshold", 0.1, "adaptive sampling subdivides pixels whose color differs from a neighbour by more than this")
+	maxDepth := flag.Int("maxdepth", 2, "how many times adaptive sampling may subdivide a pixel")
+	aperture := flag.Float64("aperture", -1, "lens diameter for depth of field, negative keeps the lens of the scene camera and 0 is a pinhole")
+	focus := flag.Float64("focus", 0, "distance to the plane in focus, 0 focuses on the point the camera looks at")
+	blades := flag.Int("blades", 0, "aperture blades for polygon shaped bokeh, fewer than 3 is round")
+	flag.StringVar(&o.projection, "projection", "perspective", "camera projection: "+strings.Join(view.Projections, ", "))
+	stereo := flag.String("stereo", "", "render a stereo pair composed as side-by-side, over-under or anaglyph")
+	interocular := flag.Float64("interocular", 0, "distance between the stereo eyes, 0 uses 1/30 of the distance to the point the camera looks at")
+	convergence := flag.Float64("convergence", 0, "distance that appears on the screen plane in stereo, 0 uses the distance to the point the camera looks at")
+	flag.IntVar(&o.glossy, "glossy", view.DefaultGlossySamples, "rays the whitted integrator spreads over rough mirror and glass surfaces")
+	flag.IntVar(&o.environmentSamples, "envsamples", -1, "directions the whitted integrator gathers environment light from per hit, 0 leaves it to material ambient and negative keeps the setting of the scene")
+	flag.StringVar(&o.environment, "environment", "", "equirectangular PNG, JPEG or PPM image to surround and light the scene with, instead of its own environment")
+	flag.StringVar(&o.heatmap, "heatmap", "", "also write an image of how many samples each pixel got to this file")
+	filterName := flag.String("filter", "box", "reconstruction filter: box, tent, gaussian or mitchell")
+	exposure := flag.Float64("exposure", 0, "exposure in stops applied before tone mapping")
+	toneMap := flag.String("tonemap", "clamp", "tone mapping operator: clamp, reinhard or aces")
+	srgb := flag.Bool("srgb", true, "encode png, jpeg and ppm output with the sRGB transfer function, false writes linear values. hdr and pfm are always linear")
+	flag.Parse()
+
+	tm, err := canvas.ParseToneMap(*toneMap)
+	if err != nil {
+		log.Fatal(err)
+	}
+	o.pipeline = canvas.Pipeline{Exposure: *exposure, ToneMap: tm, Transfer: canvas.TransferLinear}
+	if *srgb {
+		o.pipeline.Transfer = canvas.TransferSRGB
+	}
+
+	samplePattern, err := view.ParseSamplePattern(*pattern)
+	if err != nil {
+		log.Fatal(err)
+	}
+	filter, err := view.ParseFilter(*filterName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	o.sampling = view.Sampling{SamplesPerPixel: *spp, Pattern: samplePattern, Filter: filter, Threshold: *threshold, MaxDepth: *maxDepth}
+
+	if *aperture >= 0 {
+		o.lens = &view.Lens{Aperture: *aperture, FocalDistance: *focus, Blades: *blades}
+	}
+
+	o.integrator, err = view.ParseIntegrator(*integratorName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *stereo != "" {
+		layout, err := view.ParseStereoLayout(*stereo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		o.rig = &view.StereoRig{Interocular: *interocular, Convergence: *convergence, Layout: layout}
+	}
+
+	if err := run(o); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(o options) error {
+	if o.width < 1 || o.height < 1 {
+		return fmt.Errorf("image size must be positive but was %dx%d", o.width, o.height)
+	}
+	if o.workers < 1 {
+		return fmt.Errorf("need at least one worker but had %d", o.workers)
+	}
+	if o.glossy < 1 {
+		return fmt.Errorf("need at least one glossy sample but had %d", o.glossy)
+	}
+
+	format, err := outputFormat(o.out, o.format)
+	if err != nil {
+		return err
+	}
+
+	builder, err := shapes.ParseBVHBuilder(o.bvh)
+	if err != nil {
+		return err
+	}
+
+	sceneF, err := scenes.ByName(o.scene)
+	if err != nil {
+		return err
+	}
+	scene := scenes.NewScene(sceneF, builder)
+	scene.Load()
+	scene.W.SetGlossySamples(o.glossy)
+	if o.environmentSamples >= 0 {
+		scene.W.SetEnvironmentSamples(o.environmentSamples)
+	}
+	if o.environment != "" {
+		e, err := view.NewImageEnvironmentFromFile(o.environment)
+		if err != nil {
+			return err
+		}
+		scene.W.SetEnvironment(e)
+	}
+	if o.cameraIndex < 0 || o.cameraIndex >= len(scene.Cs) {
+		return fmt.Errorf("scene %s has %d cameras but camera %d was requested", o.scene, len(scene.Cs), o.cameraIndex)
+	}
+	loc := scene.Cs[o.cameraIndex]
+	if o.lens != nil {
+		loc.Lens = *o.lens
+	}
+
+	camera := loc.Camera(o.width, o.height, o.fov).WithSampling(o.sampling)
+	p, err := view.NewProjection(o.projection, camera)
+	if err != nil {
+		return err
+	}
+	camera = camera.WithProjection(p).WithIntegrator(o.integrator)
+
+	var c, heat *canvas.Canvas
+	if o.rig != nil {
+		// each eye renders at the full size, the layout decides how they are combined
+		o.rig.Camera = camera
+		left, right := o.rig.Eyes()
+		lc, lheat, err := renderImage(scene.W, left, o.bounces, o.workers)
+		if err != nil {
+			return err
+		}
+		rc, rheat, err := renderImage(scene.W, right, o.bounces, o.workers)
+		if err != nil {
+			return err
+		}
+		c = o.rig.Compose(lc, rc)
+		heat = o.rig.Compose(lheat, rheat)
+	} else {
+		c, heat, err = renderImage(scene.W, camera, o.bounces, o.workers)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.heatmap != "" {
+		if err := heat.WriteFile(o.heatmap); err != nil {
+			return fmt.Errorf("write heatmap: %w", err)
+		}
+		log.Println("wrote", o.heatmap)
+	}
+
+	f, err := os.Create(o.out)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	if err := c.EncodeWith(f, format, o.pipeline); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close output file: %w", err)
+	}
+	log.Println("wrote", o.out)
+	return nil
+}
+
+// renderImage renders the camera with progress on stderr, and an image of the samples taken for each pixel
+func renderImage(w *view.World, camera view.Camera, bounces, workers int) (*canvas.Canvas, *canvas.Canvas, error) {
+	c := canvas.NewCanvas(camera.HSize, camera.VSize)
+	heat := canvas.NewCanvas(camera.HSize, camera.VSize)
+	maxSamples := camera.Sampling.MaxSamples()
+	samples := 0
+	pc, err := view.Render(context.Background(), w, camera, bounces, workers, coordinate_supplier.Random)
+	if err != nil {
+		return nil, nil, fmt.Errorf("start render: %w", err)
+	}
+
+	total := camera.HSize * camera.VSize
+	rendered := 0
+	renderedAtLastStat := 0
+	tStart := time.Now()
+	tLastStat := tStart
+	for p := range pc {
+		c.SetPixel(p.X, p.Y, p.C)
+		heat.SetPixel(p.X, p.Y, view.HeatmapColor(p.Samples, maxSamples))
+		samples += p.Samples
+		rendered++
+
+		if since := time.Since(tLastStat); since >= time.Second {
+			log.Printf("%5.1f%% rendered, %.0f pixels/sec", 100*float64(rendered)/float64(total), float64(rendered-renderedAtLastStat)/since.Seconds())
+			renderedAtLastStat = rendered
+			tLastStat = time.Now()
+		}
+	}
+	elapsed := time.Since(tStart)
+	log.Printf("rendered %d pixels in %s, %.0f pixels/sec, %.2f samples/pixel", total, elapsed, float64(total)/elapsed.Seconds(), float64(samples)/float64(total))
+	return c, heat, nil
+}
+
+func outputFormat(out, formatName string) (canvas.Format, error) {
+	if formatName != "" {
+		return canvas.ParseFormat(formatName)
+	}
+	return canvas.FormatFromExtension(out)
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/robkau/go-raytrace/lib/shapes"
 	"io"
 	"log"
 	"math/rand"
@@ -22,13 +24,21 @@ const (
 )
 
 func main() {
+	bvh := flag.String("bvh", shapes.BVHMidpoint.String(), "bvh builder used when scenes are divided: midpoint or sah")
+	flag.Parse()
+
+	builder, err := shapes.ParseBVHBuilder(*bvh)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	//defer profile.Start(profile.CPUProfile, profile.ProfilePath(".")).Stop()
 
 	//go func() {
 	//	log.Println(http.ListenAndServe("localhost:6060", nil))
 	//}()
 
-	sb := start()
+	sb := start(builder)
 
 	// play a random intro file, sometimes.
 	rand.Seed(time.Now().UnixNano())
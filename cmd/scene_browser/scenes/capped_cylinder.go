@@ -60,7 +60,5 @@ func NewCappedCylinderScene() (*view.World, []CameraLocation) {
 	w.AddObject(walls)
 	w.AddObject(floorAndCeiling)
 
-	w.Divide(8)
-
-	return w, []CameraLocation{CameraLocation{cameraPos, cameraLookingAt}}
+	return w, []CameraLocation{{At: cameraPos, LookingAt: cameraLookingAt}}
 }
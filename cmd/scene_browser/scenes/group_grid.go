@@ -29,6 +29,5 @@ func NewGroupGridScene() (*view.World, []CameraLocation) {
 	w.AddObject(g)
 	w.AddObject(floor)
 
-	w.Divide(8)
-	return w, []CameraLocation{CameraLocation{cameraPos, cameraLookingAt}}
+	return w, []CameraLocation{{At: cameraPos, LookingAt: cameraLookingAt}}
 }
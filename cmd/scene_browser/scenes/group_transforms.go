@@ -92,7 +92,6 @@ func makeObjectGroup() shapes.Group {
 	}
 	g.AddChild(pg1)
 
-	g.Divide(8)
 	return g
 }
 
@@ -158,19 +157,13 @@ func NewGroupTransformsScene() (*view.World, []CameraLocation) {
 	g2 := makeGroupOfGroups()
 	g2.SetTransform(geom.Translate(0.5, 0, -3).MulX4Matrix(geom.RotateY(math.Pi)))
 
-	// skybox sphere
-	var skybox = shapes.NewSphere()
-	skybox.SetTransform(geom.Scale(35, 35, 35))
-	m := skybox.GetMaterial()
+	// skybox around the world, also lighting it
 	canvas, err = canvas2.CanvasFromPPMZipFile("data/ppm/satara_night_hdr.ppm.zip")
 	if err != nil {
 		panic(fmt.Sprintf("loading tokyo ppm to canvas: %s", err.Error()))
 	}
-	m.Pattern = patterns.NewTextureMapPattern(patterns.NewUVImage(canvas), patterns.SphericalMap)
-	m.Ambient = 1
-	m.Specular = 0
-	m.Diffuse = 0
-	skybox.SetMaterial(m)
+	w.SetEnvironment(view.NewImageEnvironment(canvas))
+	w.SetEnvironmentSamples(4)
 
 	// prismatic cube
 	w.AddObject(pc)
@@ -182,7 +175,6 @@ func NewGroupTransformsScene() (*view.World, []CameraLocation) {
 	//w.AddPointLight(shapes.NewPointLight(geom.NewPoint(5, 10, -3), colors.NewColor(1.9, 1.4, 1.4)))
 	w.AddObject(g1)
 	w.AddObject(g2)
-	w.AddObject(skybox)
 
-	return w, []CameraLocation{CameraLocation{cameraPos, cameraLookingAt}}
+	return w, []CameraLocation{{At: cameraPos, LookingAt: cameraLookingAt}}
 }
@@ -22,7 +22,13 @@ func NewHollowGlassSphereScene() (*view.World, []CameraLocation) {
 	wall.SetTransform(geom.Translate(0, 0, 10).MulX4Matrix(geom.RotateX(1.5708)))
 
 	ball := shapes.NewSphere()
-	ball.SetMaterial(materials.NewGlassMaterial())
+	m = materials.NewGlassMaterial()
+	// the shell looks greener where it is thicker, toward the edges
+	m.AbsorptionColor = colors.NewColor(0.6, 0.9, 0.8)
+	m.AbsorptionDistance = 1
+	// dense flint glass, the checkers fringe with color where the shell bends light the most
+	m.AbbeNumber = 20
+	ball.SetMaterial(m)
 
 	hollowCenter := shapes.NewSphere()
 	hollowCenter.SetMaterial(materials.NewGlassMaterial())
@@ -46,6 +52,5 @@ func NewHollowGlassSphereScene() (*view.World, []CameraLocation) {
 	cameraPos := geom.NewPoint(0, 0, -5)
 	cameraLookingAt := geom.ZeroPoint()
 
-	w.Divide(8)
-	return w, []CameraLocation{CameraLocation{cameraPos, cameraLookingAt}}
+	return w, []CameraLocation{{At: cameraPos, LookingAt: cameraLookingAt}}
 }
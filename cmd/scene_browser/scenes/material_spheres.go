@@ -0,0 +1,63 @@
+package scenes
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/patterns"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/robkau/go-raytrace/lib/view"
+)
+
+// NewMaterialSpheresScene lines up metallic roughness spheres, dielectrics in front and metals behind,
+// getting rougher from left to right. frosted glass and brushed mirrors with the same roughness frame them
+func NewMaterialSpheresScene() (*view.World, []CameraLocation) {
+	w := view.NewWorld()
+	cameraPos := geom.NewPoint(0, 13, -26)
+	cameraLookingAt := geom.NewPoint(0, 1, 0)
+
+	floor := shapes.NewPlane()
+	m := floor.GetMaterial()
+	m.Pattern = patterns.NewCheckerPattern(patterns.NewSolidColorPattern(colors.NewColor(0.8, 0.8, 0.8)), patterns.NewSolidColorPattern(colors.NewColor(0.3, 0.3, 0.3)))
+	m.Specular = 0
+	floor.SetMaterial(m)
+	w.AddObject(floor)
+
+	roughness := []float64{0.05, 0.25, 0.5, 0.75, 1}
+	for i, r := range roughness {
+		x := float64(i-len(roughness)/2) * 2.5
+
+		plastic := shapes.NewSphere()
+		plastic.SetTransform(geom.Translate(x, 1, -1.5))
+		plastic.SetMaterial(materials.NewMetallicRoughnessMaterial(colors.NewColor(0.8, 0.1, 0.1), 0, r))
+		w.AddObject(plastic)
+
+		gold := shapes.NewSphere()
+		gold.SetTransform(geom.Translate(x, 1, 1.5))
+		gold.SetMaterial(materials.NewMetallicRoughnessMaterial(colors.NewColor(1, 0.78, 0.34), 1, r))
+		w.AddObject(gold)
+
+		glass := shapes.NewSphere()
+		glass.SetTransform(geom.Translate(x, 0.6, -4.5).MulX4Matrix(geom.Scale(0.6, 0.6, 0.6)))
+		gm := materials.NewGlassMaterial()
+		gm.Color = colors.Black()
+		gm.Roughness = r
+		glass.SetMaterial(gm)
+		w.AddObject(glass)
+
+		mirror := shapes.NewSphere()
+		mirror.SetTransform(geom.Translate(x, 1, 4.5))
+		mm := mirror.GetMaterial()
+		mm.Color = colors.NewColor(0.1, 0.1, 0.1)
+		mm.Reflective = 0.9
+		mm.Roughness = r
+		mirror.SetMaterial(mm)
+		w.AddObject(mirror)
+	}
+
+	w.AddPointLight(shapes.NewPointLight(geom.NewPoint(-5, 10, -8), colors.NewColor(1.4, 1.4, 1.3)))
+	w.SetEnvironment(view.NewConstantEnvironment(colors.NewColor(0.25, 0.3, 0.4)))
+	w.SetEnvironmentSamples(4)
+
+	return w, []CameraLocation{{At: cameraPos, LookingAt: cameraLookingAt}}
+}
@@ -3,6 +3,7 @@ package scenes
 import (
 	"github.com/robkau/go-raytrace/lib/colors"
 	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
 	"github.com/robkau/go-raytrace/lib/patterns"
 	"github.com/robkau/go-raytrace/lib/shapes"
 	"github.com/robkau/go-raytrace/lib/view"
@@ -23,6 +24,9 @@ func NewPondScene() (*view.World, []CameraLocation) {
 	m.Transparency = 0.5
 	m.Reflective = 0.3
 	m.RefractiveIndex = 1.13333
+	// deeper water turns darker and bluer
+	m.AbsorptionColor = colors.NewColor(0.4, 0.75, 0.85)
+	m.AbsorptionDistance = 8
 	waterSurface.SetMaterial(m)
 	waterSurface.SetShadowless(true)
 	waterSurface.SetShaded(false)
@@ -60,6 +64,8 @@ func NewPondScene() (*view.World, []CameraLocation) {
 
 	// light above plane
 	w.AddPointLight(shapes.NewPointLight(geom.NewPoint(2, 12, -5), colors.NewColor(1.9, 1.4, 1.4)))
+	// mist over the water
+	w.SetFog(materials.NewMedium(colors.NewColor(0.8, 0.85, 1), 0.01, 0.03))
 	w.AddObject(waterSurface)
 	w.AddObject(dirtSurface)
 	w.AddObject(middle)
@@ -68,7 +74,5 @@ func NewPondScene() (*view.World, []CameraLocation) {
 	cameraPos := geom.NewPoint(18, 5, -10)
 	cameraLookingAt := geom.ZeroPoint()
 
-	w.Divide(8)
-
-	return w, []CameraLocation{CameraLocation{cameraPos, cameraLookingAt}}
+	return w, []CameraLocation{{At: cameraPos, LookingAt: cameraLookingAt}}
 }
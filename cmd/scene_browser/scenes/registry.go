@@ -0,0 +1,51 @@
+package scenes
+
+import (
+	"fmt"
+	"strings"
+)
+
+type NamedScene struct {
+	Name string
+	F    NewSceneFunc
+}
+
+// Registry is every scene in the order the scene browser cycles through them
+var Registry = []NamedScene{
+	{"group_transforms", NewGroupTransformsScene},
+	{"tori_replay", NewToriReplayScene},
+	{"stone_golem", NewStoneGolemScene},
+	{"wavy_carpet_spheres", NewWavyCarpetSpheres},
+	{"teapot", NewTeapotScene},
+	{"pond", NewPondScene},
+	{"capped_cylinder", NewCappedCylinderScene},
+	{"group_grid", NewGroupGridScene},
+	{"hollow_glass_sphere", NewHollowGlassSphereScene},
+	{"room", NewRoomScene},
+	{"material_spheres", NewMaterialSpheresScene},
+}
+
+func Names() []string {
+	names := make([]string, 0, len(Registry))
+	for _, s := range Registry {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func All() []NewSceneFunc {
+	fs := make([]NewSceneFunc, 0, len(Registry))
+	for _, s := range Registry {
+		fs = append(fs, s.F)
+	}
+	return fs
+}
+
+func ByName(name string) (NewSceneFunc, error) {
+	for _, s := range Registry {
+		if s.Name == name {
+			return s.F, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown scene %q, expected one of: %s", name, strings.Join(Names(), ", "))
+}
@@ -3,6 +3,7 @@ package scenes
 import (
 	"github.com/robkau/go-raytrace/lib/colors"
 	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
 	"github.com/robkau/go-raytrace/lib/shapes"
 	"github.com/robkau/go-raytrace/lib/view"
 )
@@ -24,6 +25,7 @@ func NewRoomScene() (*view.World, []CameraLocation) {
 	w := view.NewWorld()
 	cameraPos := geom.NewPoint(15, 15, 15)
 	cameraLookingAt := geom.NewPoint(0, 5, 0)
+	haze := materials.NewMedium(colors.NewColor(1, 0.95, 0.9), 0.004, 0.04)
 
 	// table made of cubes
 	tlul := sizedCubeAt(-3, 2, -3, 0.2, 2, 0.2)
@@ -60,6 +62,8 @@ func NewRoomScene() (*view.World, []CameraLocation) {
 	m.Color = colors.Brown()
 	m.Reflective = 0
 	m.Transparency = 0
+	// the room is inside both cubes, both fill it with the same dusty haze
+	m.Medium = haze
 	floorAndCeiling.SetMaterial(m)
 
 	// walls as another cube
@@ -68,13 +72,24 @@ func NewRoomScene() (*view.World, []CameraLocation) {
 	m.Reflective = 0
 	m.Transparency = 0
 	m.Color = colors.Blue()
+	m.Medium = haze
 	walls.SetMaterial(m)
 
 	// light above
 	w.AddPointLight(shapes.NewPointLight(geom.NewPoint(5, 10, -3), colors.NewColor(1.9, 1.4, 1.4)))
+
+	// spot light on the red cube
+	w.AddLight(shapes.NewSpotLight(geom.NewPoint(-1, 15, 0.75), geom.NewPoint(-1, 4.5, 0.75), 0.1, 0.2, colors.NewColor(1.5, 1.5, 1.2)))
+
+	// glowing panel on the ceiling
+	panel := sizedCubeAt(0, 19.9, 0, 4, 0.1, 4)
+	m = panel.GetMaterial()
+	m.Reflective = 0
+	m.Emission = colors.NewColor(8, 7, 6)
+	panel.SetMaterial(m)
+	w.AddObject(panel)
 	w.AddObject(floorAndCeiling)
 	w.AddObject(walls)
 
-	w.Divide(8)
-	return w, []CameraLocation{CameraLocation{cameraPos, cameraLookingAt}}
+	return w, []CameraLocation{{At: cameraPos, LookingAt: cameraLookingAt}}
 }
@@ -2,36 +2,56 @@ package scenes
 
 import (
 	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/shapes"
 	"github.com/robkau/go-raytrace/lib/view"
+	"log"
 	"math"
 	"sync/atomic"
+	"time"
 )
 
 type Scene struct {
 	W      *view.World
 	Cs     []CameraLocation
 	loadF  NewSceneFunc
+	bvh    shapes.BVHBuilder
 	loaded *atomic.Bool
 }
 
-func NewScene(loadF NewSceneFunc) *Scene {
+// groups in scene worlds are split until fewer than this many children are left in each
+const divideThreshold = 8
+
+// NewScene loads the scene on first use, dividing its world with the bvh builder
+func NewScene(loadF NewSceneFunc, bvh shapes.BVHBuilder) *Scene {
 	return &Scene{
 		W:      nil,
 		Cs:     nil,
 		loadF:  loadF,
+		bvh:    bvh,
 		loaded: &atomic.Bool{}, // todo zerovalue ok? not reference ok?
 	}
 }
 
 func (s *Scene) Load() {
 	if s.loaded.CompareAndSwap(false, true) {
+		start := time.Now()
 		s.W, s.Cs = s.loadF()
+		s.W.DivideWith(divideThreshold, s.bvh)
+		log.Println("loaded scene with", s.bvh, "bvh in", time.Since(start))
 	}
 }
 
 type CameraLocation struct {
 	At        geom.Tuple
 	LookingAt geom.Tuple
+	// zero value is a pinhole camera
+	Lens view.Lens
+	// zero value fires one ray through each pixel center
+	Sampling view.Sampling
+}
+
+func (l *CameraLocation) Camera(width, height int, fov float64) view.Camera {
+	return view.NewCameraAt(width, height, fov, l.At, l.LookingAt).WithLens(l.Lens).WithSampling(l.Sampling)
 }
 
 func (l *CameraLocation) RotateAroundX(radians float64) {
@@ -66,10 +86,10 @@ func (l *CameraLocation) RotateAroundZ(radians float64) {
 
 type NewSceneFunc func() (*view.World, []CameraLocation)
 
-func LoadScenes(fs ...NewSceneFunc) []*Scene {
+func LoadScenes(bvh shapes.BVHBuilder, fs ...NewSceneFunc) []*Scene {
 	scenes := []*Scene{}
 	for _, initF := range fs {
-		scenes = append(scenes, NewScene(initF))
+		scenes = append(scenes, NewScene(initF, bvh))
 	}
 	return scenes
 }
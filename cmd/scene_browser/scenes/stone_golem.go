@@ -104,8 +104,6 @@ func NewStoneGolemScene() (*view.World, []CameraLocation) {
 	//w.AddPointLight(shapes.NewPointLight(geom.NewPoint(0, 13, 0), colors.NewColor(1.9, 1.4, 1.4)))
 	w.AddPointLight(shapes.NewPointLight(cameraPos, colors.NewColor(1.9, 1.4, 1.4)))
 
-	w.Divide(64)
-
 	return w, []CameraLocation{CameraLocation{
 		At:        cameraPos,
 		LookingAt: cameraLookingAt,
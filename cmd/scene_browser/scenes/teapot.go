@@ -65,9 +65,7 @@ func NewTeapotScene() (*view.World, []CameraLocation) {
 	w.AddObject(floorAndCeiling)
 	//w.AddObject(walls)
 
-	w.Divide(16)
-
 	w.AddPointLight(shapes.NewPointLight(cameraPos, colors.NewColor(1.9, 1.4, 1.4)))
 
-	return w, []CameraLocation{CameraLocation{cameraPos, cameraLookingAt}}
+	return w, []CameraLocation{{At: cameraPos, LookingAt: cameraLookingAt}}
 }
@@ -141,8 +141,16 @@ func NewToriReplayScene() (*view.World, []CameraLocation) {
 
 	cLookingAt := geom.NewPoint(0, c.Y, c.Z).Add(geom.NewPoint(0, 1.25, 0))
 
-	w.Divide(8)
-	return w, basicRotatedCameras(cLookingAt, cameraDistance)
+	cameras := basicRotatedCameras(cLookingAt, cameraDistance)
+
+	// look down the lineup of marbles with the front one in focus
+	front := geom.NewPoint(0, 2, 0)
+	cameras = append(cameras, CameraLocation{
+		At:        front.Add(geom.NewVector(sceneSpacing*3, sceneSpacing, -sceneSpacing*3)),
+		LookingAt: front,
+		Lens:      view.Lens{Aperture: 1.5, Blades: 6},
+	})
+	return w, cameras
 }
 
 func basicRotatedCameras(lookingAt geom.Tuple, distance float64) []CameraLocation {
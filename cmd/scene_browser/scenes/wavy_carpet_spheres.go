@@ -82,6 +82,5 @@ func NewWavyCarpetSpheres() (*view.World, []CameraLocation) {
 	cameraPos := geom.NewPoint(2, 4, -3)
 	cameraLookingAt := geom.NewPoint(0, 1, 0)
 
-	w.Divide(16)
-	return w, []CameraLocation{{cameraPos, cameraLookingAt}}
+	return w, []CameraLocation{{At: cameraPos, LookingAt: cameraLookingAt}}
 }
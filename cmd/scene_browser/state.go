@@ -8,6 +8,7 @@ import (
 	"github.com/robkau/coordinate_supplier"
 	"github.com/robkau/go-raytrace/cmd/scene_browser/scenes"
 	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/shapes"
 	"github.com/robkau/go-raytrace/lib/view"
 	"github.com/robkau/go-raytrace/lib/view/canvas"
 	"log"
@@ -26,24 +27,24 @@ type state struct {
 	loc              *scenes.CameraLocation
 	scenes           []*scenes.Scene
 	canvas           *canvas.Canvas
+	toneMap          int
+	exposure         float64
+	srgb             bool
+	projection       int32
+	integrator       int32
+	// passes accumulated into the canvas since the last change
+	passes      int32
+	shownPasses int32
 
 	cancel context.CancelFunc
 }
 
-func start() *state {
+// stop adding passes once the image has converged, even deterministic renders gain anti-aliasing until then
+const maxAccumulatedPasses = 256
+
+func start(bvh shapes.BVHBuilder) *state {
 	s := &state{
-		scenes: scenes.LoadScenes(
-			scenes.NewGroupTransformsScene,
-			scenes.NewToriReplayScene,
-			scenes.NewStoneGolemScene,
-			scenes.NewWavyCarpetSpheres,
-			scenes.NewTeapotScene,
-			scenes.NewPondScene,
-			scenes.NewCappedCylinderScene,
-			scenes.NewGroupGridScene,
-			scenes.NewHollowGlassSphereScene,
-			scenes.NewRoomScene,
-		),
+		scenes: scenes.LoadScenes(bvh, scenes.All()...),
 		canvas: canvas.NewCanvas(width, width),
 		loc: &scenes.CameraLocation{
 			At:        geom.NewPoint(2, 2, 2),
@@ -51,6 +52,7 @@ func start() *state {
 		},
 		rayBounces:       3,
 		renderGoroutines: int32(runtime.NumCPU() / 3),
+		srgb:             true,
 	}
 
 	var rendered uint32 = 0
@@ -80,18 +82,41 @@ func start() *state {
 
 			}
 			log.Println("camera at", s.loc.At, "pointed to", s.loc.LookingAt)
-			pc, err := view.Render(ctx, s.scenes[s.currentScene].W, view.NewCameraAt(width, width, fov, s.loc.At, s.loc.LookingAt), int(bounces), int(renderGoroutines), coordinate_supplier.Random)
+			camera := s.loc.Camera(width, width, fov)
+			projection, err := view.NewProjection(view.Projections[atomic.LoadInt32(&s.projection)], camera)
 			if err != nil {
-				fmt.Println("failed create render")
 				log.Fatalf(err.Error())
 			}
-			tLastRenderStat := time.Now()
-			for p := range pc {
-				if n := atomic.AddUint32(&rendered, 1); n%pixelsPerRenderStat == 0 {
-					fmt.Printf("Writing %f pixels/sec\n", float64(pixelsPerRenderStat)/time.Since(tLastRenderStat).Seconds())
-					tLastRenderStat = time.Now()
+			camera = camera.WithProjection(projection).WithIntegrator(view.Integrators[atomic.LoadInt32(&s.integrator)])
+
+			// every pass adds one more sample per pixel to the running mean, until something changes
+			acc := canvas.NewAccumulator(width, width)
+			atomic.StoreInt32(&s.passes, 0)
+			for pass := 0; pass < maxAccumulatedPasses && ctx.Err() == nil; pass++ {
+				// each pass samples the configured pattern with its own random numbers, so a jittered pattern also anti-aliases.
+				// every worker seeds from the pass seed upward
+				passCamera := camera
+				passCamera.Sampling.Seed = camera.Sampling.Seed + int64(pass+1)*int64(renderGoroutines+1)
+				pc, err := view.Render(ctx, s.scenes[s.currentScene].W, passCamera, int(bounces), int(renderGoroutines), coordinate_supplier.Random)
+				if err != nil {
+					fmt.Println("failed create render")
+					log.Fatalf(err.Error())
+				}
+				tLastRenderStat := time.Now()
+				tRenderStart := time.Now()
+				for p := range pc {
+					if n := atomic.AddUint32(&rendered, 1); n%pixelsPerRenderStat == 0 {
+						fmt.Printf("Writing %f pixels/sec\n", float64(pixelsPerRenderStat)/time.Since(tLastRenderStat).Seconds())
+						tLastRenderStat = time.Now()
+					}
+					s.canvas.SetPixel(p.X, p.Y, acc.Add(p.X, p.Y, p.C))
+				}
+				if ctx.Err() == nil {
+					atomic.StoreInt32(&s.passes, int32(pass+1))
+					if pass == 0 {
+						log.Println("rendered frame in", time.Since(tRenderStart))
+					}
 				}
-				s.canvas.SetPixel(p.X, p.Y, p.C)
 			}
 
 			select {
@@ -107,6 +132,11 @@ func start() *state {
 func (s *state) Update() error {
 	s.frameCount++
 
+	if passes := atomic.LoadInt32(&s.passes); passes != s.shownPasses {
+		s.shownPasses = passes
+		ebiten.SetWindowTitle(fmt.Sprintf("go-raytrace - %d samples per pixel", passes))
+	}
+
 	//if inpututil.IsKeyJustPressed(ebiten.KeyW) {
 	//	s.loc.At = s.loc.At.Sub(s.loc.At.Sub(s.loc.LookingAt).Normalize())
 	//	s.cancel()
@@ -239,14 +269,62 @@ func (s *state) Update() error {
 		s.canvas = canvas.NewCanvas(width, width)
 	}
 
+	// next camera projection
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		p := (atomic.LoadInt32(&s.projection) + 1) % int32(len(view.Projections))
+		atomic.StoreInt32(&s.projection, p)
+		log.Println(view.Projections[p], "projection")
+		s.cancel()
+		s.canvas = canvas.NewCanvas(width, width)
+	}
+
+	// next integrator
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		i := (atomic.LoadInt32(&s.integrator) + 1) % int32(len(view.Integrators))
+		atomic.StoreInt32(&s.integrator, i)
+		log.Println(view.Integrators[i].Name(), "integrator")
+		s.cancel()
+		s.canvas = canvas.NewCanvas(width, width)
+	}
+
+	// display pipeline only changes how the canvas is drawn, no need to render again
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		s.toneMap = (s.toneMap + 1) % len(canvas.ToneMaps)
+		log.Println(canvas.ToneMaps[s.toneMap].Name, "tone mapping")
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		s.srgb = !s.srgb
+		log.Println("srgb transfer", s.srgb)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageUp) {
+		s.exposure += 0.5
+		log.Println("exposure", s.exposure)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyPageDown) {
+		s.exposure -= 0.5
+		log.Println("exposure", s.exposure)
+	}
+
 	// canvas is updated in background goroutine.
 	return nil
 }
 
+func (s *state) pipeline() canvas.Pipeline {
+	p := canvas.Pipeline{
+		Exposure: s.exposure,
+		ToneMap:  canvas.ToneMaps[s.toneMap].F,
+		Transfer: canvas.TransferLinear,
+	}
+	if s.srgb {
+		p.Transfer = canvas.TransferSRGB
+	}
+	return p
+}
+
 func (s *state) Draw(screen *ebiten.Image) {
 	// render current frame progress
 	op := &ebiten.DrawImageOptions{}
-	screen.DrawImage(ebiten.NewImageFromImage(s.canvas.ToImage()), op)
+	screen.DrawImage(ebiten.NewImageFromImage(s.canvas.ToImageWith(s.pipeline())), op)
 }
 
 func (s *state) Layout(outsideWidth, outsideHeight int) (int, int) {
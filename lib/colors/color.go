@@ -151,6 +151,11 @@ func (c Color) MulBy(x float64) Color {
 	}
 }
 
+// Luminance is the brightness of the linear color as seen by the eye, with Rec. 709 weights
+func (c Color) Luminance() float64 {
+	return 0.2126*c.R + 0.7152*c.G + 0.0722*c.B
+}
+
 func (c Color) Equal(other Color) bool {
 	return geom.AlmostEqualWithPrecision(c.R, other.R, 1e-3) &&
 		geom.AlmostEqualWithPrecision(c.G, other.G, 1e-3) &&
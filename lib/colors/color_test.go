@@ -42,3 +42,10 @@ func Test_ColorMulBy(t *testing.T) {
 
 	assert.True(t, Color{0.4, 0.6, 0.8}.Equal(b))
 }
+
+func Test_ColorLuminance(t *testing.T) {
+	assert.InDelta(t, 1, White().Luminance(), 1e-9)
+	assert.InDelta(t, 0, Black().Luminance(), 1e-9)
+	assert.True(t, Green().Luminance() > Red().Luminance())
+	assert.True(t, Red().Luminance() > Blue().Luminance())
+}
@@ -0,0 +1,193 @@
+package geom
+
+import (
+	"math"
+	"sort"
+)
+
+// closed-form polynomial root solvers, adapted from Jochen Schwarze's "Cubic and Quartic Roots" in Graphics Gems I.
+// every root is polished with a few newton iterations on the original polynomial to win back lost precision.
+// repeated roots are returned once for each multiplicity, so a ray grazing a surface still enters and leaves it.
+
+const (
+	polynomialEpsilon = 1e-9
+	polishIterations  = 2
+)
+
+// SolveQuadratic returns the sorted real roots of ax^2 + bx + c = 0
+func SolveQuadratic(a, b, c float64) []float64 {
+	if isZero(a) {
+		if isZero(b) {
+			return nil
+		}
+		return []float64{-c / b}
+	}
+
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		if !isZero(disc) {
+			return nil
+		}
+		disc = 0
+	}
+
+	// avoid subtracting two nearly equal numbers
+	q := -0.5 * (b + math.Copysign(math.Sqrt(disc), b))
+	if q == 0 {
+		// b and disc are both zero
+		return []float64{0, 0}
+	}
+
+	return sortedRoots(q/a, c/q)
+}
+
+// SolveCubic returns the sorted real roots of ax^3 + bx^2 + cx + d = 0
+func SolveCubic(a, b, c, d float64) []float64 {
+	if isZero(a) {
+		return SolveQuadratic(b, c, d)
+	}
+
+	roots := solveNormalizedCubic(b/a, c/a, d/a)
+	for i := range roots {
+		roots[i] = polish(roots[i], a, b, c, d)
+	}
+	return sortedRoots(roots...)
+}
+
+// SolveQuartic returns the sorted real roots of ax^4 + bx^3 + cx^2 + dx + e = 0
+func SolveQuartic(a, b, c, d, e float64) []float64 {
+	if isZero(a) {
+		return SolveCubic(b, c, d, e)
+	}
+
+	roots := solveNormalizedQuartic(b/a, c/a, d/a, e/a)
+	for i := range roots {
+		roots[i] = polish(roots[i], a, b, c, d, e)
+	}
+	return sortedRoots(roots...)
+}
+
+// solveNormalizedCubic solves x^3 + ax^2 + bx + c = 0
+func solveNormalizedCubic(a, b, c float64) []float64 {
+	// substitute x = y - a/3 to eliminate the quadratic term: y^3 + 3py + 2q = 0
+	sqA := a * a
+	p := 1.0 / 3 * (-1.0/3*sqA + b)
+	q := 1.0 / 2 * (2.0/27*a*sqA - 1.0/3*a*b + c)
+
+	// use Cardano's formula
+	cbP := p * p * p
+	disc := q*q + cbP
+
+	var roots []float64
+	if isZero(disc) {
+		if isZero(q) {
+			// one triple solution
+			roots = []float64{0, 0, 0}
+		} else {
+			// one single and one double solution
+			u := math.Cbrt(-q)
+			roots = []float64{2 * u, -u, -u}
+		}
+	} else if disc < 0 {
+		// three real solutions
+		phi := 1.0 / 3 * math.Acos(-q/math.Sqrt(-cbP))
+		t := 2 * math.Sqrt(-p)
+		roots = []float64{
+			t * math.Cos(phi),
+			-t * math.Cos(phi+math.Pi/3),
+			-t * math.Cos(phi-math.Pi/3),
+		}
+	} else {
+		// one real solution
+		sqrtD := math.Sqrt(disc)
+		u := math.Cbrt(sqrtD - q)
+		v := -math.Cbrt(sqrtD + q)
+		roots = []float64{u + v}
+	}
+
+	// resubstitute
+	sub := 1.0 / 3 * a
+	for i := range roots {
+		roots[i] -= sub
+	}
+	return roots
+}
+
+// solveNormalizedQuartic solves x^4 + ax^3 + bx^2 + cx + d = 0
+func solveNormalizedQuartic(a, b, c, d float64) []float64 {
+	// substitute x = y - a/4 to eliminate the cubic term: y^4 + py^2 + qy + r = 0
+	sqA := a * a
+	p := -3.0/8*sqA + b
+	q := 1.0/8*sqA*a - 1.0/2*a*b + c
+	r := -3.0/256*sqA*sqA + 1.0/16*sqA*b - 1.0/4*a*c + d
+
+	var roots []float64
+	if isZero(r) {
+		// no absolute term: y(y^3 + py + q) = 0
+		roots = append(solveNormalizedCubic(0, p, q), 0)
+	} else {
+		// solve the resolvent cubic and take one real solution to build two quadratic equations
+		z := solveNormalizedCubic(-1.0/2*p, -r, 1.0/2*r*p-1.0/8*q*q)[0]
+
+		u := z*z - r
+		v := 2*z - p
+
+		if isZero(u) {
+			u = 0
+		} else if u > 0 {
+			u = math.Sqrt(u)
+		} else {
+			return nil
+		}
+
+		if isZero(v) {
+			v = 0
+		} else if v > 0 {
+			v = math.Sqrt(v)
+		} else {
+			return nil
+		}
+
+		if q < 0 {
+			v = -v
+		}
+		roots = append(SolveQuadratic(1, v, z-u), SolveQuadratic(1, -v, z+u)...)
+	}
+
+	// resubstitute
+	sub := 1.0 / 4 * a
+	for i := range roots {
+		roots[i] -= sub
+	}
+	return roots
+}
+
+// polish refines root x of the polynomial with the given coefficients (highest degree first)
+func polish(x float64, coefficients ...float64) float64 {
+	for i := 0; i < polishIterations; i++ {
+		f := 0.0
+		df := 0.0
+		for _, c := range coefficients {
+			df = df*x + f
+			f = f*x + c
+		}
+		if df == 0 {
+			break
+		}
+		next := x - f/df
+		if math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		x = next
+	}
+	return x
+}
+
+func sortedRoots(roots ...float64) []float64 {
+	sort.Float64s(roots)
+	return roots
+}
+
+func isZero(f float64) bool {
+	return math.Abs(f) < polynomialEpsilon
+}
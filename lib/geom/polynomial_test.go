@@ -0,0 +1,93 @@
+package geom
+
+import (
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func Test_SolveQuadratic(t *testing.T) {
+	type args struct {
+		a, b, c float64
+		expect  []float64
+	}
+
+	tests := []args{
+		{1, -3, 2, []float64{1, 2}},
+		{2, 0, -8, []float64{-2, 2}},
+		{1, -2, 1, []float64{1, 1}},
+		{1, 0, 0, []float64{0, 0}},
+		{1, 0, 1, nil},
+		{0, 2, -4, []float64{2}},
+		{1, 1e8, 1, []float64{-1e8, -1e-8}},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			requireRoots(t, tt.expect, SolveQuadratic(tt.a, tt.b, tt.c))
+		})
+	}
+}
+
+func Test_SolveCubic(t *testing.T) {
+	type args struct {
+		a, b, c, d float64
+		expect     []float64
+	}
+
+	tests := []args{
+		// (x-1)(x-2)(x-3)
+		{1, -6, 11, -6, []float64{1, 2, 3}},
+		// 2(x+1)(x^2+1)
+		{2, 2, 2, 2, []float64{-1}},
+		// (x-2)^2(x+1)
+		{1, -3, 0, 4, []float64{-1, 2, 2}},
+		// x^3
+		{1, 0, 0, 0, []float64{0, 0, 0}},
+		// degenerate to quadratic
+		{0, 1, -3, 2, []float64{1, 2}},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			requireRoots(t, tt.expect, SolveCubic(tt.a, tt.b, tt.c, tt.d))
+		})
+	}
+}
+
+func Test_SolveQuartic(t *testing.T) {
+	type args struct {
+		a, b, c, d, e float64
+		expect        []float64
+	}
+
+	tests := []args{
+		// (x-1)(x-2)(x-3)(x-4)
+		{1, -10, 35, -50, 24, []float64{1, 2, 3, 4}},
+		// (x^2+1)(x-2)(x+3)
+		{1, 1, -5, 1, -6, []float64{-3, 2}},
+		// (x^2+1)(x^2+4)
+		{1, 0, 5, 0, 4, nil},
+		// 3x(x-1)(x+1)(x-5)
+		{3, -15, -3, 15, 0, []float64{-1, 0, 1, 5}},
+		// (x-1)^2(x+2)^2
+		{1, 2, -3, -4, 4, []float64{-2, -2, 1, 1}},
+		// widely spread roots (x-0.001)(x-1)(x-100)(x-1000)
+		{1, -1101.001, 101101.101, -100101.1, 100, []float64{0.001, 1, 100, 1000}},
+		// degenerate to cubic
+		{0, 1, -6, 11, -6, []float64{1, 2, 3}},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			requireRoots(t, tt.expect, SolveQuartic(tt.a, tt.b, tt.c, tt.d, tt.e))
+		})
+	}
+}
+
+func requireRoots(t *testing.T, expect []float64, actual []float64) {
+	require.Len(t, actual, len(expect), "roots: %v", actual)
+	for i := range expect {
+		require.InDelta(t, expect[i], actual[i], 1e-6, "roots: %v", actual)
+	}
+}
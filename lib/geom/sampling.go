@@ -0,0 +1,84 @@
+package geom
+
+import "math"
+
+// warps from uniform random numbers in [0, 1) to other shapes, keeping the distribution uniform
+
+// SampleDisk maps u, v onto the unit disk with Shirley and Chiu's concentric mapping, which keeps strata compact
+func SampleDisk(u, v float64) (float64, float64) {
+	// map to [-1, 1]^2
+	a := 2*u - 1
+	b := 2*v - 1
+	if a == 0 && b == 0 {
+		return 0, 0
+	}
+
+	var r, theta float64
+	if math.Abs(a) > math.Abs(b) {
+		r = a
+		theta = math.Pi / 4 * (b / a)
+	} else {
+		r = b
+		theta = math.Pi/2 - math.Pi/4*(a/b)
+	}
+	return r * math.Cos(theta), r * math.Sin(theta)
+}
+
+// SamplePolygon maps u, v, w onto a regular polygon with its corners on the unit circle.
+// w picks one of the triangles fanning out from the center, u and v pick a point inside it
+func SamplePolygon(sides int, rotation float64, u, v, w float64) (float64, float64) {
+	if sides < 3 {
+		return SampleDisk(u, v)
+	}
+
+	i := int(w * float64(sides))
+	if i >= sides {
+		i = sides - 1
+	}
+	step := 2 * math.Pi / float64(sides)
+	a0 := rotation + float64(i)*step
+	a1 := a0 + step
+
+	// uniform point in the triangle (center, corner a0, corner a1)
+	su := math.Sqrt(u)
+	b0 := su * (1 - v)
+	b1 := su * v
+	return b0*math.Cos(a0) + b1*math.Cos(a1), b0*math.Sin(a0) + b1*math.Sin(a1)
+}
+
+// SampleCosineHemisphere maps u, v onto the hemisphere around +z, denser toward the pole in proportion to cos(theta)
+func SampleCosineHemisphere(u, v float64) Tuple {
+	x, y := SampleDisk(u, v)
+	z := math.Sqrt(math.Max(0, 1-x*x-y*y))
+	return NewVector(x, y, z)
+}
+
+// SampleSphere maps u, v onto the unit sphere
+func SampleSphere(u, v float64) Tuple {
+	z := 1 - 2*u
+	r := math.Sqrt(math.Max(0, 1-z*z))
+	phi := 2 * math.Pi * v
+	return NewVector(r*math.Cos(phi), r*math.Sin(phi), z)
+}
+
+// SampleCone maps u, v onto the directions within a cone around +z, out to the angle with cosine cosMax
+func SampleCone(u, v, cosMax float64) Tuple {
+	cosTheta := 1 - u*(1-cosMax)
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := 2 * math.Pi * v
+	return NewVector(sinTheta*math.Cos(phi), sinTheta*math.Sin(phi), cosTheta)
+}
+
+// OrthonormalBasis returns two unit vectors perpendicular to the unit vector n and to each other
+func OrthonormalBasis(n Tuple) (Tuple, Tuple) {
+	// Duff et al. "Building an Orthonormal Basis, Revisited"
+	sign := math.Copysign(1, n.Z)
+	a := -1 / (sign + n.Z)
+	b := n.X * n.Y * a
+	return NewVector(1+sign*n.X*n.X*a, sign*b, -sign*n.X), NewVector(b, sign+n.Y*n.Y*a, -n.Y)
+}
+
+// ToBasis turns a vector around +z into the same vector around n, with t and b from OrthonormalBasis
+func ToBasis(v, t, b, n Tuple) Tuple {
+	return t.Mul(v.X).Add(b.Mul(v.Y)).Add(n.Mul(v.Z))
+}
@@ -0,0 +1,138 @@
+package geom
+
+import (
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func Test_SampleDisk(t *testing.T) {
+	type args struct {
+		u, v   float64
+		ex, ey float64
+	}
+
+	tests := []args{
+		{0.5, 0.5, 0, 0},
+		{1, 0.5, 1, 0},
+		{0, 0.5, -1, 0},
+		{0.5, 1, 0, 1},
+		{0.5, 0, 0, -1},
+		{1, 1, math.Sqrt2 / 2, math.Sqrt2 / 2},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			x, y := SampleDisk(tt.u, tt.v)
+
+			require.InDelta(t, tt.ex, x, 1e-9)
+			require.InDelta(t, tt.ey, y, 1e-9)
+		})
+	}
+}
+
+func Test_SampleDisk_Uniform(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	n := 20000
+	inner := 0
+	for i := 0; i < n; i++ {
+		x, y := SampleDisk(rng.Float64(), rng.Float64())
+		r := math.Sqrt(x*x + y*y)
+		require.LessOrEqual(t, r, 1+1e-9)
+		if r < math.Sqrt(0.5) {
+			inner++
+		}
+	}
+
+	// half the area of the unit disk is within radius sqrt(1/2)
+	require.InDelta(t, 0.5, float64(inner)/float64(n), 0.02)
+}
+
+func Test_SamplePolygon(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sides := 6
+	apothem := math.Cos(math.Pi / float64(sides))
+	for i := 0; i < 5000; i++ {
+		x, y := SamplePolygon(sides, 0, rng.Float64(), rng.Float64(), rng.Float64())
+
+		// inside every edge of the hexagon
+		for k := 0; k < sides; k++ {
+			mid := (float64(k) + 0.5) * 2 * math.Pi / float64(sides)
+			require.LessOrEqual(t, x*math.Cos(mid)+y*math.Sin(mid), apothem+1e-9)
+		}
+	}
+
+	x, y := SamplePolygon(4, math.Pi/4, 1, 0, 0)
+	require.InDelta(t, math.Sqrt2/2, x, 1e-9)
+	require.InDelta(t, math.Sqrt2/2, y, 1e-9)
+
+	x, y = SamplePolygon(0, 0, 1, 0.5, 0)
+	require.InDelta(t, 1, x, 1e-9)
+	require.InDelta(t, 0, y, 1e-9)
+}
+
+func Test_SampleCosineHemisphere(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	n := 20000
+	sumCos := 0.0
+	for i := 0; i < n; i++ {
+		v := SampleCosineHemisphere(rng.Float64(), rng.Float64())
+		require.InDelta(t, 1, v.Mag(), 1e-9)
+		require.GreaterOrEqual(t, v.Z, 0.0)
+		sumCos += v.Z
+	}
+
+	// the mean of cos(theta) over a cosine weighted hemisphere is 2/3
+	require.InDelta(t, 2./3, sumCos/float64(n), 0.01)
+}
+
+func Test_SampleSphere(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	n := 20000
+	sum := NewVector(0, 0, 0)
+	for i := 0; i < n; i++ {
+		v := SampleSphere(rng.Float64(), rng.Float64())
+		require.InDelta(t, 1, v.Mag(), 1e-9)
+		sum = sum.Add(v)
+	}
+
+	// no direction is preferred
+	require.InDelta(t, 0, sum.Mag()/float64(n), 0.02)
+}
+
+func Test_SampleCone(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	cosMax := math.Cos(0.3)
+	for i := 0; i < 1000; i++ {
+		v := SampleCone(rng.Float64(), rng.Float64(), cosMax)
+		require.InDelta(t, 1, v.Mag(), 1e-9)
+		require.GreaterOrEqual(t, v.Z, cosMax-1e-9)
+	}
+	require.Equal(t, NewVector(0, 0, 1), SampleCone(0, 0.5, cosMax))
+}
+
+func Test_OrthonormalBasis(t *testing.T) {
+	normals := []Tuple{
+		NewVector(0, 0, 1),
+		NewVector(0, 0, -1),
+		NewVector(1, 0, 0),
+		NewVector(0, 1, 0),
+		NewVector(1, 2, 3).Normalize(),
+		NewVector(-0.3, 0.1, -0.9).Normalize(),
+	}
+
+	for ti, n := range normals {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			tv, b := OrthonormalBasis(n)
+
+			require.InDelta(t, 1, tv.Mag(), 1e-9)
+			require.InDelta(t, 1, b.Mag(), 1e-9)
+			require.InDelta(t, 0, tv.Dot(n), 1e-9)
+			require.InDelta(t, 0, b.Dot(n), 1e-9)
+			require.InDelta(t, 0, tv.Dot(b), 1e-9)
+			require.Equal(t, n.RoundTo(9), ToBasis(NewVector(0, 0, 1), tv, b, n).RoundTo(9))
+		})
+	}
+}
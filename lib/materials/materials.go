@@ -1,10 +1,42 @@
 package materials
 
 import (
+	"fmt"
 	"github.com/robkau/go-raytrace/lib/colors"
 	"github.com/robkau/go-raytrace/lib/patterns"
+	"math"
 )
 
+// Model is how a material reflects light
+type Model uint8
+
+const (
+	// ModelPhong shades with Ambient, Diffuse, Specular and Shininess
+	ModelPhong Model = iota
+	// ModelMetallicRoughness is a microfacet model shaded with Color, Metallic and Roughness, as in glTF
+	ModelMetallicRoughness
+)
+
+func (m Model) String() string {
+	switch m {
+	case ModelPhong:
+		return "phong"
+	case ModelMetallicRoughness:
+		return "metallic-roughness"
+	default:
+		return fmt.Sprintf("Model(%d)", m)
+	}
+}
+
+func ParseModel(s string) (Model, error) {
+	for _, m := range []Model{ModelPhong, ModelMetallicRoughness} {
+		if s == m.String() {
+			return m, nil
+		}
+	}
+	return ModelPhong, fmt.Errorf("unknown material model %q", s)
+}
+
 type Material struct {
 	Color           colors.Color
 	Pattern         patterns.Pattern
@@ -15,6 +47,26 @@ type Material struct {
 	Reflective      float64
 	Transparency    float64
 	RefractiveIndex float64
+	// Emission is light given off by the surface, which makes the shape a light source
+	Emission colors.Color
+	// Model picks the shading, the zero value is phong
+	Model Model
+	// Metallic blends from a dielectric with a white highlight, 0, to a metal tinted by Color, 1
+	Metallic float64
+	// Roughness spreads the highlight from a mirror, 0, to fully diffuse, 1.
+	// it also blurs Reflective and Transparency into brushed metal and frosted glass
+	Roughness float64
+	// AbsorptionColor is the color white light fades to after travelling AbsorptionDistance through the inside
+	// of a transparent shape, following the Beer-Lambert law. a zero distance absorbs nothing
+	AbsorptionColor    colors.Color
+	AbsorptionDistance float64
+	// AbbeNumber spreads RefractiveIndex over the wavelengths of light, lower splits colors further.
+	// crown glass is around 60 and flint glass around 30, zero refracts every color the same
+	AbbeNumber float64
+	// CauchyB is the second coefficient of Cauchy's equation in square micrometers, used when AbbeNumber is zero
+	CauchyB float64
+	// Medium fills the inside of the shape, the zero value is empty space
+	Medium Medium
 }
 
 func NewMaterial() Material {
@@ -30,6 +82,16 @@ func NewMaterial() Material {
 	}
 }
 
+// NewMetallicRoughnessMaterial is a microfacet material. Ambient is kept for the whitted integrator
+func NewMetallicRoughnessMaterial(color colors.Color, metallic, roughness float64) Material {
+	m := NewMaterial()
+	m.Model = ModelMetallicRoughness
+	m.Color = color
+	m.Metallic = metallic
+	m.Roughness = roughness
+	return m
+}
+
 func NewGlassMaterial() Material {
 	m := NewMaterial()
 	m.Color = colors.NewColor(0, 0, 0.2)
@@ -51,7 +113,77 @@ func IsZeroMaterial(m Material) bool {
 		m.Shininess == 0 &&
 		m.Reflective == 0 &&
 		m.Transparency == 0 &&
-		m.RefractiveIndex == 0
+		m.RefractiveIndex == 0 &&
+		m.Emission == colors.Color{} &&
+		m.Model == ModelPhong &&
+		m.Metallic == 0 &&
+		m.Roughness == 0 &&
+		m.AbsorptionColor == colors.Color{} &&
+		m.AbsorptionDistance == 0 &&
+		m.AbbeNumber == 0 &&
+		m.CauchyB == 0 &&
+		m.Medium == Medium{}
+}
+
+func IsEmissive(m Material) bool {
+	return m.Emission.R > 0 || m.Emission.G > 0 || m.Emission.B > 0
+}
+
+func IsAbsorbing(m Material) bool {
+	return m.AbsorptionDistance > 0
+}
+
+// Transmittance is the fraction of light left after travelling distance through the material
+func Transmittance(m Material, distance float64) colors.Color {
+	if !IsAbsorbing(m) || distance <= 0 {
+		return colors.White()
+	}
+	k := distance / m.AbsorptionDistance
+	return colors.NewColor(fade(m.AbsorptionColor.R, k), fade(m.AbsorptionColor.G, k), fade(m.AbsorptionColor.B, k))
+}
+
+// fade raises the color left after one absorption distance to the number of distances travelled
+func fade(left, distances float64) float64 {
+	if left >= 1 {
+		return 1
+	}
+	if left <= 0 {
+		return 0
+	}
+	return math.Pow(left, distances)
+}
+
+// wavelengths of the Fraunhofer lines that RefractiveIndex and AbbeNumber are measured at, in nanometers
+const (
+	WavelengthF = 486.1
+	WavelengthD = 587.6
+	WavelengthC = 656.3
+)
+
+func IsDispersive(m Material) bool {
+	return m.AbbeNumber > 0 || m.CauchyB != 0
+}
+
+// RefractiveIndexAt is the refractive index for light of a wavelength in nanometers, with RefractiveIndex at WavelengthD
+func RefractiveIndexAt(m Material, wavelength float64) float64 {
+	if !IsDispersive(m) || wavelength <= 0 {
+		return m.RefractiveIndex
+	}
+	return m.RefractiveIndex + cauchyB(m)*(inverseSquare(wavelength)-inverseSquare(WavelengthD))
+}
+
+func cauchyB(m Material) float64 {
+	if m.AbbeNumber > 0 {
+		// the abbe number is how far the index climbs from C to F relative to how far it is from 1
+		return (m.RefractiveIndex - 1) / m.AbbeNumber / (inverseSquare(WavelengthF) - inverseSquare(WavelengthC))
+	}
+	return m.CauchyB
+}
+
+// inverseSquare is 1 / wavelength^2 with the wavelength in micrometers
+func inverseSquare(nanometers float64) float64 {
+	um := nanometers / 1000
+	return 1 / (um * um)
 }
 
 func ZeroMaterial() Material {
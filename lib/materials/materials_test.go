@@ -3,6 +3,7 @@ package materials
 import (
 	"github.com/robkau/go-raytrace/lib/colors"
 	"github.com/stretchr/testify/assert"
+	"strconv"
 	"testing"
 )
 
@@ -18,3 +19,103 @@ func Test_DefaultMaterial(t *testing.T) {
 	assert.Equal(t, 0.0, m.Transparency)
 	assert.Equal(t, 1.0, m.RefractiveIndex)
 }
+
+func Test_IsZeroMaterial_Emission(t *testing.T) {
+	m := ZeroMaterial()
+	assert.True(t, IsZeroMaterial(m))
+	assert.False(t, IsEmissive(m))
+
+	m.Emission = colors.NewColor(0, 0.5, 0)
+	assert.False(t, IsZeroMaterial(m))
+	assert.True(t, IsEmissive(m))
+	assert.False(t, IsEmissive(NewMaterial()))
+}
+
+func Test_NewMetallicRoughnessMaterial(t *testing.T) {
+	m := NewMetallicRoughnessMaterial(colors.Red(), 1, 0.3)
+
+	assert.Equal(t, ModelMetallicRoughness, m.Model)
+	assert.Equal(t, colors.Red(), m.Color)
+	assert.Equal(t, 1.0, m.Metallic)
+	assert.Equal(t, 0.3, m.Roughness)
+	assert.Equal(t, ModelPhong, NewMaterial().Model)
+	assert.False(t, IsZeroMaterial(Material{Model: ModelMetallicRoughness}))
+}
+
+func Test_ParseModel(t *testing.T) {
+	for _, m := range []Model{ModelPhong, ModelMetallicRoughness} {
+		parsed, err := ParseModel(m.String())
+		assert.NoError(t, err)
+		assert.Equal(t, m, parsed)
+	}
+	_, err := ParseModel("lambert")
+	assert.Error(t, err)
+}
+
+func Test_Transmittance(t *testing.T) {
+	m := NewMaterial()
+	assert.False(t, IsAbsorbing(m))
+	assert.Equal(t, colors.White(), Transmittance(m, 10))
+
+	m.AbsorptionColor = colors.NewColor(0.5, 1, 0)
+	m.AbsorptionDistance = 2
+	assert.True(t, IsAbsorbing(m))
+	assert.False(t, IsZeroMaterial(Material{AbsorptionDistance: 1}))
+
+	type args struct {
+		distance float64
+		expect   colors.Color
+	}
+
+	tests := []args{
+		{distance: 0, expect: colors.White()},
+		{distance: 2, expect: colors.NewColor(0.5, 1, 0)},
+		{distance: 4, expect: colors.NewColor(0.25, 1, 0)},
+		{distance: 1, expect: colors.NewColor(0.70711, 1, 0)},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			assert.Equal(t, tt.expect, Transmittance(m, tt.distance).RoundTo(5))
+		})
+	}
+}
+
+func Test_RefractiveIndexAt(t *testing.T) {
+	m := NewGlassMaterial()
+	assert.False(t, IsDispersive(m))
+	assert.Equal(t, 1.5, RefractiveIndexAt(m, 450))
+
+	// bk7 crown glass
+	m.RefractiveIndex = 1.5168
+	m.AbbeNumber = 64.17
+	assert.True(t, IsDispersive(m))
+	assert.False(t, IsZeroMaterial(Material{AbbeNumber: 30}))
+
+	type args struct {
+		wavelength float64
+		expect     float64
+	}
+
+	tests := []args{
+		{wavelength: WavelengthD, expect: 1.5168},
+		{wavelength: 0, expect: 1.5168},
+		{wavelength: WavelengthF, expect: 1.5224},
+		{wavelength: WavelengthC, expect: 1.5143},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			assert.InDelta(t, tt.expect, RefractiveIndexAt(m, tt.wavelength), 2e-4)
+		})
+	}
+
+	// the abbe number comes back out of the spread
+	spread := RefractiveIndexAt(m, WavelengthF) - RefractiveIndexAt(m, WavelengthC)
+	assert.InDelta(t, 64.17, (m.RefractiveIndex-1)/spread, 1e-9)
+
+	// cauchy's coefficient directly
+	m.AbbeNumber = 0
+	m.CauchyB = 0.00420
+	assert.InDelta(t, 1.5168+0.0042*(1/0.45/0.45-1/0.5876/0.5876), RefractiveIndexAt(m, 450), 1e-12)
+}
@@ -0,0 +1,49 @@
+package materials
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"math"
+)
+
+// Medium is smoke, fog or haze that absorbs and scatters the light passing through it.
+// it fills the inside of a shape through the material, make the shape shadowless so light from outside reaches in
+type Medium struct {
+	// Absorption is the chance per unit distance that light is absorbed
+	Absorption float64
+	// Scattering is the chance per unit distance that light bounces off the medium
+	Scattering float64
+	// Color tints the light scattered toward the eye
+	Color colors.Color
+}
+
+func NewMedium(color colors.Color, absorption, scattering float64) Medium {
+	return Medium{
+		Absorption: absorption,
+		Scattering: scattering,
+		Color:      color,
+	}
+}
+
+func IsParticipating(m Medium) bool {
+	return m.Absorption > 0 || m.Scattering > 0
+}
+
+// MediumTransmittance is the fraction of light that crosses distance through the medium without being absorbed or scattered
+func MediumTransmittance(m Medium, distance float64) float64 {
+	if !IsParticipating(m) || distance <= 0 {
+		return 1
+	}
+	return math.Exp(-(m.Absorption + m.Scattering) * distance)
+}
+
+// NewMediumMaterial is an invisible surface around a medium
+func NewMediumMaterial(medium Medium) Material {
+	m := NewMaterial()
+	m.Color = colors.Black()
+	m.Ambient = 0
+	m.Diffuse = 0
+	m.Specular = 0
+	m.Transparency = 1
+	m.Medium = medium
+	return m
+}
@@ -0,0 +1,34 @@
+package materials
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func Test_MediumTransmittance(t *testing.T) {
+	assert.False(t, IsParticipating(Medium{}))
+	assert.Equal(t, 1.0, MediumTransmittance(Medium{}, 100))
+
+	m := NewMedium(colors.White(), 0.1, 0.4)
+	assert.True(t, IsParticipating(m))
+	assert.Equal(t, 1.0, MediumTransmittance(m, 0))
+	assert.InDelta(t, math.Exp(-1), MediumTransmittance(m, 2), 1e-12)
+	assert.InDelta(t, math.Exp(-2), MediumTransmittance(m, 4), 1e-12)
+}
+
+func Test_NewMediumMaterial(t *testing.T) {
+	medium := NewMedium(colors.NewColor(0.8, 0.8, 0.9), 0, 0.2)
+	m := NewMediumMaterial(medium)
+
+	assert.Equal(t, medium, m.Medium)
+	// the surface itself is neither seen nor bends light
+	assert.Equal(t, 1.0, m.Transparency)
+	assert.Equal(t, 1.0, m.RefractiveIndex)
+	assert.Equal(t, 0.0, m.Reflective)
+	assert.Equal(t, 0.0, m.Diffuse)
+	assert.Equal(t, 0.0, m.Specular)
+	assert.Equal(t, 0.0, m.Ambient)
+	assert.False(t, IsZeroMaterial(Material{Medium: medium}))
+}
@@ -1,6 +1,7 @@
 package patterns
 
 import (
+	"fmt"
 	"github.com/robkau/go-raytrace/lib/colors"
 	"github.com/robkau/go-raytrace/lib/geom"
 	"github.com/robkau/go-raytrace/lib/view/canvas"
@@ -73,6 +74,15 @@ func NewUVImage(c *canvas.Canvas) *UVImage {
 	return &UVImage{canvas: c}
 }
 
+// NewUVImageFromFile loads a PNG, JPEG or PPM texture. zipped files are unzipped first
+func NewUVImageFromFile(path string) (*UVImage, error) {
+	c, err := canvas.CanvasFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load uv image: %w", err)
+	}
+	return NewUVImage(c), nil
+}
+
 func (i *UVImage) ColorAt(u, v float64) colors.Color {
 	// flip v over so it matches the image layout, with y at the top
 	v = 1 - v
@@ -244,6 +254,24 @@ func CylindricalMap(p geom.Tuple) (u, v float64) {
 	return
 }
 
+// NewToroidalMap maps points on a torus with the given major radius, lying around the y axis.
+// u goes around the ring and v goes around the tube.
+func NewToroidalMap(majorRadius float64) UvMappingF {
+	return func(p geom.Tuple) (u, v float64) {
+		// compute the azimuthal angle, same as with spherical_map()
+		theta := math.Atan2(p.X, p.Z)
+		rawU := theta / (2 * math.Pi)
+		u = 1 - (rawU + 0.5)
+
+		// angle around the tube, measured from the outer equator
+		// -π < phi <= π
+		phi := math.Atan2(p.Y, math.Sqrt(p.X*p.X+p.Z*p.Z)-majorRadius)
+		v = phi/(2*math.Pi) + 0.5
+
+		return
+	}
+}
+
 func remainderOfOneCloserToZero(v float64) float64 {
 	var flipped bool
 	if v < 0 {
@@ -6,6 +6,7 @@ import (
 	"github.com/robkau/go-raytrace/lib/view/canvas"
 	"github.com/stretchr/testify/require"
 	"math"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -122,6 +123,32 @@ func Test_CylindricalMapping_3dPoint(t *testing.T) {
 	}
 }
 
+func Test_ToroidalMapping_3dPoint(t *testing.T) {
+
+	type tc struct {
+		p         geom.Tuple
+		expectedU float64
+		expectedV float64
+	}
+
+	tcs := []tc{
+		{geom.NewPoint(0, 0, -2.5), 0.0, 0.5},
+		{geom.NewPoint(0, 0.5, -2), 0.0, 0.75},
+		{geom.NewPoint(2.5, 0, 0), 0.25, 0.5},
+		{geom.NewPoint(0, -0.5, 2), 0.5, 0.25},
+		{geom.NewPoint(-1.5, 0, 0), 0.75, 1.0},
+	}
+
+	mapper := NewToroidalMap(2)
+	for i, tc := range tcs {
+		t.Run(t.Name()+strconv.Itoa(i), func(t *testing.T) {
+			u, v := mapper(tc.p)
+			require.True(t, geom.AlmostEqual(tc.expectedU, u))
+			require.True(t, geom.AlmostEqual(tc.expectedV, v))
+		})
+	}
+}
+
 func Test_TextureMapping_SphericalMap(t *testing.T) {
 	type tc struct {
 		p             geom.Tuple
@@ -422,3 +449,22 @@ func Test_UVImage_FromPPMCanvas(t *testing.T) {
 	}
 
 }
+
+func Test_UVImage_FromPNGFile(t *testing.T) {
+	c := canvas.NewCanvas(2, 2)
+	c.SetPixel(0, 0, colors.White())
+	c.SetPixel(1, 1, colors.NewColor(1, 0, 0))
+	p := filepath.Join(t.TempDir(), "texture.png")
+	require.NoError(t, c.WriteFile(p))
+
+	pattern, err := NewUVImageFromFile(p)
+	require.NoError(t, err)
+
+	// v is flipped so the top left pixel is at u=0 v=1
+	require.Equal(t, colors.White(), UvPatternAt(pattern, 0, 1))
+	require.Equal(t, colors.NewColor(1, 0, 0), UvPatternAt(pattern, 1, 0))
+	require.Equal(t, colors.Black(), UvPatternAt(pattern, 1, 1))
+
+	_, err = NewUVImageFromFile(filepath.Join(t.TempDir(), "missing.png"))
+	require.Error(t, err)
+}
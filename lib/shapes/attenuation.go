@@ -0,0 +1,78 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"math"
+)
+
+// Attenuation dims a light with the distance d to it, by 1 / (Constant + Linear*d + Quadratic*d*d).
+// the zero value does not attenuate, so lights reach everything at full intensity
+type Attenuation struct {
+	Constant  float64
+	Linear    float64
+	Quadratic float64
+}
+
+// InverseSquare is the physically based falloff of a light from a point
+func InverseSquare() Attenuation {
+	return Attenuation{Quadratic: 1}
+}
+
+func (a Attenuation) At(d float64) float64 {
+	if a == (Attenuation{}) {
+		return 1
+	}
+	denom := a.Constant + a.Linear*d + a.Quadratic*d*d
+	if denom <= 0 {
+		// right on top of an inverse square light, infinite light would turn into NaN colors
+		return 1
+	}
+	return 1 / denom
+}
+
+// LuminousEfficacy is the lumens in a watt of light at 555nm, the peak of human vision
+const LuminousEfficacy = 683.0
+
+// Light intensities follow the Phong lighting convention: a white surface with diffuse 1 facing a light of intensity 1
+// shows a color of 1, without the 1/π of a lambertian surface. an inverse square light of intensity 1 lights a surface
+// 1 unit away as brightly as a directional light of intensity 1.
+// the conversions below keep lights comparable with each other, they are not calibrated against emissive materials
+// or the environment, which are π times dimmer for the same power.
+// colors are tints, scaled so their luminance carries the whole power and differently tinted lights stay comparable
+
+// WattsToIntensity is the intensity of a light radiating watts evenly in every direction
+func WattsToIntensity(watts float64, tint colors.Color) colors.Color {
+	return normalizedTint(tint).MulBy(watts / (4 * math.Pi))
+}
+
+// LumensToIntensity is the intensity of a light with a luminous flux in lumens, like on a light bulb box
+func LumensToIntensity(lumens float64, tint colors.Color) colors.Color {
+	return WattsToIntensity(lumens/LuminousEfficacy, tint)
+}
+
+// SpotWattsToIntensity concentrates the watts of a spot light into its cone, using the average of both cone angles
+func SpotWattsToIntensity(watts float64, innerAngle, outerAngle float64, tint colors.Color) colors.Color {
+	solidAngle := 2 * math.Pi * (1 - math.Cos((innerAngle+outerAngle)/2))
+	if solidAngle <= 0 {
+		return colors.Black()
+	}
+	return normalizedTint(tint).MulBy(watts / solidAngle)
+}
+
+// IrradianceToIntensity is the intensity of a directional light delivering W/m² to a surface facing it
+func IrradianceToIntensity(wattsPerSquareMeter float64, tint colors.Color) colors.Color {
+	return normalizedTint(tint).MulBy(wattsPerSquareMeter)
+}
+
+// LuxToIntensity is the intensity of a directional light with an illuminance in lux, around 100000 for direct sun
+func LuxToIntensity(lux float64, tint colors.Color) colors.Color {
+	return IrradianceToIntensity(lux/LuminousEfficacy, tint)
+}
+
+func normalizedTint(tint colors.Color) colors.Color {
+	l := tint.Luminance()
+	if l <= 0 {
+		return colors.Black()
+	}
+	return tint.MulBy(1 / l)
+}
@@ -0,0 +1,72 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func Test_Attenuation_At(t *testing.T) {
+	type args struct {
+		a      Attenuation
+		d      float64
+		expect float64
+	}
+
+	tests := []args{
+		{Attenuation{}, 100, 1},
+		{InverseSquare(), 2, 0.25},
+		{InverseSquare(), 0.5, 4},
+		{InverseSquare(), 0, 1},
+		{Attenuation{Constant: 1, Linear: 0.5}, 2, 0.5},
+		{Attenuation{Constant: 1, Linear: 0.1, Quadratic: 0.01}, 10, 1.0 / 3},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			require.InDelta(t, tt.expect, tt.a.At(tt.d), 1e-9)
+		})
+	}
+}
+
+func Test_Lighting_InverseSquare(t *testing.T) {
+	m := materials.NewMaterial()
+	m.Ambient = 0
+	m.Specular = 0
+	eyeV := geom.NewVector(0, 0, -1)
+	normalV := geom.NewVector(0, 0, -1)
+	near := NewPointLight(geom.NewPoint(0, 0, -2), colors.White()).WithAttenuation(InverseSquare())
+	far := NewPointLight(geom.NewPoint(0, 0, -4), colors.White()).WithAttenuation(InverseSquare())
+	spot := NewSpotLight(geom.NewPoint(0, 0, -4), geom.ZeroPoint(), 0.1, 0.2, colors.White()).WithAttenuation(InverseSquare())
+
+	cNear := Lighting(m, NewSphere(), near, geom.ZeroPoint(), eyeV, normalV, 1.0)
+	cFar := Lighting(m, NewSphere(), far, geom.ZeroPoint(), eyeV, normalV, 1.0)
+	cSpot := Lighting(m, NewSphere(), spot, geom.ZeroPoint(), eyeV, normalV, 1.0)
+
+	require.InDelta(t, 0.9/4, cNear.R, 1e-9)
+	require.InDelta(t, 0.9/16, cFar.R, 1e-9)
+	require.InDelta(t, 0.9/16, cSpot.R, 1e-9)
+}
+
+func Test_PhotometricUnits(t *testing.T) {
+	// 683 lumens is one watt
+	require.InDelta(t, 1/(4*math.Pi), LumensToIntensity(LuminousEfficacy, colors.White()).G, 1e-9)
+	require.Equal(t, WattsToIntensity(1, colors.White()), LumensToIntensity(LuminousEfficacy, colors.White()))
+
+	// equal power in any tint looks equally bright
+	red := LumensToIntensity(800, colors.Red())
+	white := LumensToIntensity(800, colors.White())
+	require.InDelta(t, white.Luminance(), red.Luminance(), 1e-9)
+	require.Equal(t, 0.0, red.G)
+
+	// the sun at 100000 lux is about 146 W/m²
+	require.InDelta(t, 146.4, LuxToIntensity(100000, colors.White()).R, 0.1)
+
+	// a spot light focuses its power into the cone
+	require.True(t, SpotWattsToIntensity(10, 0.2, 0.3, colors.White()).R > WattsToIntensity(10, colors.White()).R)
+	require.Equal(t, colors.Black(), WattsToIntensity(10, colors.Black()))
+}
@@ -140,3 +140,13 @@ func (b *BoundingBox) SplitBounds() (left, right *BoundingBox) {
 func (b *BoundingBox) Center() geom.Tuple {
 	return b.Min.Add(b.Max).Div(2)
 }
+
+func (b *BoundingBox) SurfaceArea() float64 {
+	dx := b.Max.X - b.Min.X
+	dy := b.Max.Y - b.Min.Y
+	dz := b.Max.Z - b.Min.Z
+	if dx < 0 || dy < 0 || dz < 0 {
+		return 0
+	}
+	return 2 * (dx*dy + dy*dz + dz*dx)
+}
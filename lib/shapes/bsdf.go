@@ -0,0 +1,95 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"math"
+)
+
+// BSDF is how a surface point scatters light arriving from any direction toward the eye,
+// for integrators that gather light from many directions instead of a few lights
+type BSDF interface {
+	// Eval is the share of light arriving along wi that leaves toward the eye, per steradian
+	Eval(wi geom.Tuple) colors.Color
+	// Pdf is the density Sample picks wi with
+	Pdf(wi geom.Tuple) float64
+	// Sample maps u, v, w in [0, 1) to a direction light arrives from. u picks the lobe
+	Sample(u, v, w float64) (geom.Tuple, bool)
+}
+
+// NewBSDF is the BSDF of the material at world point p, seen along eyev
+func NewBSDF(m materials.Material, s Shape, p, eyev, normalv geom.Tuple) BSDF {
+	base := SurfaceColor(m, s, p)
+	if m.Model == materials.ModelMetallicRoughness {
+		return newMicrofacetBSDF(m, base, normalv, eyev)
+	}
+	return newPhongBSDF(m, base, normalv, eyev)
+}
+
+// phongBSDF reflects light like phong lighting does for lights:
+// lambertian diffuse plus the energy normalized phong lobe around the mirror direction
+type phongBSDF struct {
+	albedo    colors.Color
+	specular  float64
+	shininess float64
+	normal    geom.Tuple
+	mirror    geom.Tuple
+	// chance of sampling the diffuse part
+	pDiffuse float64
+}
+
+func newPhongBSDF(m materials.Material, base colors.Color, normal, eyev geom.Tuple) phongBSDF {
+	albedo := base.MulBy(m.Diffuse)
+	diffuse := math.Max(0, (albedo.R+albedo.G+albedo.B)/3)
+	specular := math.Max(0, m.Specular)
+	pDiffuse := 1.0
+	if diffuse+specular > 0 {
+		pDiffuse = diffuse / (diffuse + specular)
+	}
+	return phongBSDF{
+		albedo:    albedo,
+		specular:  specular,
+		shininess: math.Max(0, m.Shininess),
+		normal:    normal,
+		mirror:    eyev.Neg().Reflect(normal),
+		pDiffuse:  pDiffuse,
+	}
+}
+
+func (b phongBSDF) Eval(wi geom.Tuple) colors.Color {
+	if wi.Dot(b.normal) <= 0 {
+		return colors.Black()
+	}
+	f := b.albedo.MulBy(1 / math.Pi)
+	if cos := wi.Dot(b.mirror); b.specular > 0 && cos > 0 {
+		lobe := b.specular * (b.shininess + 2) / (2 * math.Pi) * math.Pow(cos, b.shininess)
+		f = f.Add(colors.White().MulBy(lobe))
+	}
+	return f
+}
+
+func (b phongBSDF) Pdf(wi geom.Tuple) float64 {
+	pdf := 0.0
+	if cos := wi.Dot(b.normal); cos > 0 {
+		pdf += b.pDiffuse * cos / math.Pi
+	}
+	if cos := wi.Dot(b.mirror); cos > 0 {
+		pdf += (1 - b.pDiffuse) * (b.shininess + 1) / (2 * math.Pi) * math.Pow(cos, b.shininess)
+	}
+	return pdf
+}
+
+func (b phongBSDF) Sample(u, v, w float64) (geom.Tuple, bool) {
+	if u < b.pDiffuse {
+		t, bt := geom.OrthonormalBasis(b.normal)
+		return geom.ToBasis(geom.SampleCosineHemisphere(v, w), t, bt, b.normal), true
+	}
+
+	cosTheta := math.Pow(v, 1/(b.shininess+1))
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := 2 * math.Pi * w
+	t, bt := geom.OrthonormalBasis(b.mirror)
+	d := geom.ToBasis(geom.NewVector(sinTheta*math.Cos(phi), sinTheta*math.Sin(phi), cosTheta), t, bt, b.mirror)
+	return d, d.Dot(b.normal) > 0
+}
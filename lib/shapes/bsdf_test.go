@@ -0,0 +1,49 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func Test_NewBSDF_PicksModel(t *testing.T) {
+	n := geom.NewVector(0, 1, 0)
+
+	_, ok := NewBSDF(materials.NewMaterial(), NewSphere(), geom.ZeroPoint(), n, n).(phongBSDF)
+	require.True(t, ok)
+	_, ok = NewBSDF(materials.NewMetallicRoughnessMaterial(colors.White(), 0, 0.5), NewSphere(), geom.ZeroPoint(), n, n).(microfacetBSDF)
+	require.True(t, ok)
+}
+
+func Test_PhongBSDF(t *testing.T) {
+	normal := geom.NewVector(0, 1, 0)
+	eye := geom.NewVector(1, 1, 0).Normalize()
+	m := materials.NewMaterial()
+	m.Shininess = 20
+	b := NewBSDF(m, NewSphere(), geom.ZeroPoint(), eye, normal)
+	rng := rand.New(rand.NewSource(1))
+
+	// the density of the sampled lobes integrates to one over the hemisphere
+	sum := 0.0
+	n := 100000
+	for i := 0; i < n; i++ {
+		sum += b.Pdf(geom.SampleSphere(rng.Float64(), rng.Float64())) * 4 * math.Pi
+	}
+	require.InDelta(t, 1, sum/float64(n), 0.05)
+
+	// the highlight is brightest in the mirror direction
+	mirror := geom.NewVector(-1, 1, 0).Normalize()
+	require.True(t, b.Eval(mirror).R > b.Eval(normal).R)
+	require.Equal(t, colors.Black(), b.Eval(geom.NewVector(0, -1, 0)))
+
+	for i := 0; i < 1000; i++ {
+		if wi, ok := b.Sample(rng.Float64(), rng.Float64(), rng.Float64()); ok {
+			require.True(t, wi.Dot(normal) > 0)
+			require.True(t, b.Pdf(wi) > 0)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package shapes
+
+import (
+	"fmt"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"math"
+	"sort"
+)
+
+// BVHBuilder picks how groups are split into subgroups when they are divided
+type BVHBuilder uint8
+
+const (
+	// BVHMidpoint cuts the longest axis of the group bounds in half.
+	// children crossing the cut stay in the parent group.
+	BVHMidpoint BVHBuilder = iota
+	// BVHSAH sorts children by centroid and splits where the surface area heuristic is cheapest.
+	// every child ends up in a subgroup.
+	BVHSAH
+)
+
+const sahBins = 12
+
+func (b BVHBuilder) String() string {
+	switch b {
+	case BVHMidpoint:
+		return "midpoint"
+	case BVHSAH:
+		return "sah"
+	default:
+		return fmt.Sprintf("BVHBuilder(%d)", b)
+	}
+}
+
+func ParseBVHBuilder(s string) (BVHBuilder, error) {
+	switch s {
+	case "midpoint":
+		return BVHMidpoint, nil
+	case "sah":
+		return BVHSAH, nil
+	default:
+		return BVHMidpoint, fmt.Errorf("unknown bvh builder %q", s)
+	}
+}
+
+// partitionSAH moves all children of g into two new groups, split along the axis and position
+// with the lowest surface area heuristic cost found by binning child centroids.
+func partitionSAH(g *group) (left, right Group) {
+	boxes := make([]*BoundingBox, len(g.children))
+	for i, c := range g.children {
+		boxes[i] = ParentSpaceBoundsOf(c)
+	}
+
+	order, split := sahSplit(boxes)
+
+	left = NewGroup()
+	right = NewGroup()
+	for k, i := range order {
+		if k < split {
+			left.AddChild(g.children[i])
+		} else {
+			right.AddChild(g.children[i])
+		}
+	}
+	g.children = g.children[:0]
+	return
+}
+
+// sahSplit sorts the boxes by centroid and picks where to split them.
+// order[:split] goes left and order[split:] goes right, both sides are never empty for 2 or more boxes.
+func sahSplit(boxes []*BoundingBox) (order []int, split int) {
+	n := len(boxes)
+	centroids := make([]geom.Tuple, n)
+	centroidBounds := NewEmptyBoundingBox()
+	for i, b := range boxes {
+		centroids[i] = finiteCenter(b)
+		centroidBounds.Add(centroids[i])
+	}
+
+	bestAxis, bestBin := -1, 0
+	bestCost := math.Inf(1)
+	for axis := 0; axis < 3; axis++ {
+		lo, hi := axisOf(centroidBounds.Min, axis), axisOf(centroidBounds.Max, axis)
+		if hi <= lo {
+			continue
+		}
+
+		var counts [sahBins]int
+		var bounds [sahBins]*BoundingBox
+		for i := range bounds {
+			bounds[i] = NewEmptyBoundingBox()
+		}
+		for i := range boxes {
+			b := binOf(axisOf(centroids[i], axis), lo, hi)
+			counts[b]++
+			bounds[b].AddBoundingBoxes(boxes[i])
+		}
+
+		// empty bins are skipped, adding an empty box would grow the sum to infinity
+		// sweep from the right to know the cost of everything after each split
+		var rightCounts [sahBins]int
+		var rightAreas [sahBins]float64
+		acc := NewEmptyBoundingBox()
+		count := 0
+		for i := sahBins - 1; i > 0; i-- {
+			if counts[i] > 0 {
+				count += counts[i]
+				acc.AddBoundingBoxes(bounds[i])
+			}
+			rightCounts[i] = count
+			rightAreas[i] = acc.SurfaceArea()
+		}
+
+		acc = NewEmptyBoundingBox()
+		count = 0
+		for i := 0; i < sahBins-1; i++ {
+			if counts[i] > 0 {
+				count += counts[i]
+				acc.AddBoundingBoxes(bounds[i])
+			}
+			if count == 0 || rightCounts[i+1] == 0 {
+				continue
+			}
+			cost := float64(count)*acc.SurfaceArea() + float64(rightCounts[i+1])*rightAreas[i+1]
+			if cost < bestCost {
+				bestCost = cost
+				bestAxis = axis
+				bestBin = i
+			}
+		}
+	}
+
+	axis := bestAxis
+	if axis < 0 {
+		axis = longestAxis(centroidBounds)
+	}
+
+	order = make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return axisOf(centroids[order[i]], axis) < axisOf(centroids[order[j]], axis)
+	})
+
+	// boxes sorted by centroid fall into bins in order, so the split is a prefix of the sorted boxes
+	split = n / 2
+	if bestAxis >= 0 {
+		lo, hi := axisOf(centroidBounds.Min, axis), axisOf(centroidBounds.Max, axis)
+		split = 0
+		for _, i := range order {
+			if binOf(axisOf(centroids[i], axis), lo, hi) > bestBin {
+				break
+			}
+			split++
+		}
+		if split == 0 || split == n {
+			split = n / 2
+		}
+	}
+	return order, split
+}
+
+// midpointSplit sorts the boxes by centroid along the longest axis of their bounds and splits them at its middle.
+// both sides are never empty for 2 or more boxes
+func midpointSplit(boxes []*BoundingBox) (order []int, split int) {
+	n := len(boxes)
+	centroids := make([]geom.Tuple, n)
+	bounds := NewEmptyBoundingBox()
+	for i, b := range boxes {
+		centroids[i] = finiteCenter(b)
+		bounds.AddBoundingBoxes(b)
+	}
+	axis := longestAxis(bounds)
+
+	order = make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return axisOf(centroids[order[i]], axis) < axisOf(centroids[order[j]], axis)
+	})
+
+	mid := (axisOf(bounds.Min, axis) + axisOf(bounds.Max, axis)) / 2
+	for split < n && axisOf(centroids[order[split]], axis) < mid {
+		split++
+	}
+	if split == 0 || split == n {
+		split = n / 2
+	}
+	return order, split
+}
+
+// split picks how the builder divides the boxes, order[:split] goes left and order[split:] goes right
+func (b BVHBuilder) split(boxes []*BoundingBox) (order []int, split int) {
+	if b == BVHSAH {
+		return sahSplit(boxes)
+	}
+	return midpointSplit(boxes)
+}
+
+func binOf(v, lo, hi float64) int {
+	b := int(sahBins * (v - lo) / (hi - lo))
+	if b < 0 {
+		return 0
+	}
+	if b >= sahBins {
+		return sahBins - 1
+	}
+	return b
+}
+
+// finiteCenter is the center of the box, with unbounded axes (like planes) placed at zero
+func finiteCenter(b *BoundingBox) geom.Tuple {
+	c := b.Center()
+	if math.IsInf(c.X, 0) || math.IsNaN(c.X) {
+		c.X = 0
+	}
+	if math.IsInf(c.Y, 0) || math.IsNaN(c.Y) {
+		c.Y = 0
+	}
+	if math.IsInf(c.Z, 0) || math.IsNaN(c.Z) {
+		c.Z = 0
+	}
+	return c
+}
+
+func axisOf(t geom.Tuple, axis int) float64 {
+	switch axis {
+	case 0:
+		return t.X
+	case 1:
+		return t.Y
+	default:
+		return t.Z
+	}
+}
+
+func longestAxis(b *BoundingBox) int {
+	dx := b.Max.X - b.Min.X
+	dy := b.Max.Y - b.Min.Y
+	dz := b.Max.Z - b.Min.Z
+	if dx >= dy && dx >= dz {
+		return 0
+	}
+	if dy >= dz {
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,145 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func Test_ParseBVHBuilder(t *testing.T) {
+	for _, b := range []BVHBuilder{BVHMidpoint, BVHSAH} {
+		parsed, err := ParseBVHBuilder(b.String())
+		require.NoError(t, err)
+		require.Equal(t, b, parsed)
+	}
+
+	_, err := ParseBVHBuilder("octree")
+	require.Error(t, err)
+}
+
+func Test_Group_DivideSAH_SplitsClusters(t *testing.T) {
+	g := NewGroup()
+	var leftCluster, rightCluster []Shape
+	for i := 0; i < 4; i++ {
+		s := NewSphere()
+		s.SetTransform(geom.Translate(-10+float64(i)*0.1, 0, 0))
+		leftCluster = append(leftCluster, s)
+		g.AddChild(s)
+	}
+	for i := 0; i < 2; i++ {
+		s := NewSphere()
+		s.SetTransform(geom.Translate(10+float64(i)*0.1, 0, 0))
+		rightCluster = append(rightCluster, s)
+		g.AddChild(s)
+	}
+
+	g.DivideWith(8, BVHSAH)
+	// below the threshold nothing happens
+	require.Len(t, g.GetChildren(), 6)
+
+	g.DivideWith(6, BVHSAH)
+	require.Len(t, g.GetChildren(), 2)
+	require.ElementsMatch(t, leftCluster, g.GetChildren()[0].(Group).GetChildren())
+	require.ElementsMatch(t, rightCluster, g.GetChildren()[1].(Group).GetChildren())
+}
+
+func Test_Group_DivideSAH_KeepsNoChildInParent(t *testing.T) {
+	// the midpoint builder leaves s3 in the parent because it crosses the split
+	s1 := NewSphere()
+	s1.SetTransform(geom.Translate(-2, -2, 0))
+	s2 := NewSphere()
+	s2.SetTransform(geom.Translate(-2, 2, 0))
+	s3 := NewSphere()
+	s3.SetTransform(geom.Scale(4, 4, 4))
+
+	g := NewGroup()
+	g.AddChild(s1)
+	g.AddChild(s2)
+	g.AddChild(s3)
+
+	g.DivideWith(1, BVHSAH)
+
+	require.Len(t, g.GetChildren(), 2)
+	for _, c := range g.GetChildren() {
+		_, ok := c.(Group)
+		require.True(t, ok)
+	}
+	require.ElementsMatch(t, []Shape{s1, s2, s3}, leaves(g))
+}
+
+func Test_Group_DivideSAH_SameCentroids(t *testing.T) {
+	g := NewGroup()
+	var children []Shape
+	for i := 0; i < 5; i++ {
+		s := NewSphere()
+		children = append(children, s)
+		g.AddChild(s)
+	}
+
+	g.DivideWith(2, BVHSAH)
+
+	require.ElementsMatch(t, children, leaves(g))
+	require.Len(t, g.GetChildren(), 2)
+	require.Len(t, g.GetChildren()[0].(Group).GetChildren(), 2)
+	require.Len(t, g.GetChildren()[1].(Group).GetChildren(), 2)
+}
+
+func Test_Group_DivideSAH_SameIntersections(t *testing.T) {
+	build := func() Group {
+		g := NewGroup()
+		for x := -3; x <= 3; x++ {
+			for y := -3; y <= 3; y++ {
+				s := NewSphere()
+				s.SetTransform(geom.Translate(float64(x)*2.5, float64(y)*2.5, 0).MulX4Matrix(geom.Scale(0.5+float64(x+3)*0.1, 1, 1)))
+				g.AddChild(s)
+			}
+		}
+		g.AddChild(NewPlane())
+		return g
+	}
+
+	reference := build()
+	midpoint := build()
+	midpoint.DivideWith(2, BVHMidpoint)
+	sah := build()
+	sah.DivideWith(2, BVHSAH)
+
+	rays := []geom.Ray{
+		geom.RayWith(geom.NewPoint(0, 0, -10), geom.NewVector(0, 0, 1)),
+		geom.RayWith(geom.NewPoint(2.5, -2.5, -10), geom.NewVector(0, 0, 1)),
+		geom.RayWith(geom.NewPoint(-20, 0.1, 0), geom.NewVector(1, 0, 0)),
+		geom.RayWith(geom.NewPoint(-20, -20, -1), geom.NewVector(1, 1, 0.05).Normalize()),
+	}
+
+	for ti, r := range rays {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			expect := reference.Intersect(r)
+			for _, g := range []Group{midpoint, sah} {
+				xs := g.Intersect(r)
+				require.Len(t, xs.I, len(expect.I))
+				for i := range expect.I {
+					require.Equal(t, expect.I[i].T, xs.I[i].T)
+				}
+			}
+		})
+	}
+}
+
+func Test_BoundingBox_SurfaceArea(t *testing.T) {
+	require.Equal(t, 24.0, NewBoundingBox(geom.NewPoint(-1, -1, -1), geom.NewPoint(1, 1, 1)).SurfaceArea())
+	require.Equal(t, 22.0, NewBoundingBox(geom.NewPoint(0, 0, 0), geom.NewPoint(1, 2, 3)).SurfaceArea())
+	require.Equal(t, 0.0, NewEmptyBoundingBox().SurfaceArea())
+}
+
+func leaves(g Group) []Shape {
+	var found []Shape
+	for _, c := range g.GetChildren() {
+		if cg, ok := c.(Group); ok {
+			found = append(found, leaves(cg)...)
+		} else {
+			found = append(found, c)
+		}
+	}
+	return found
+}
@@ -67,6 +67,10 @@ func (c *Cone) Intersect(r geom.Ray) *Intersections {
 	return Intersect(r, c.t, c.LocalIntersect)
 }
 
+func (c *Cone) AnyHit(r geom.Ray, maxT float64) bool {
+	return AnyHit(c, r, maxT, c.LocalIntersect)
+}
+
 func (c *Cone) LocalIntersect(r geom.Ray) *Intersections {
 	xs := NewIntersections()
 
@@ -0,0 +1,255 @@
+package shapes
+
+import (
+	"github.com/google/uuid"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+)
+
+type CSGOperation uint8
+
+const (
+	CSGUnion CSGOperation = iota
+	CSGIntersection
+	CSGDifference
+)
+
+// CSG combines two shapes with a constructive solid geometry operation.
+// It implements Group so the left and right shapes can use it as their parent.
+type CSG struct {
+	t          *geom.X4Matrix
+	parent     Group
+	op         CSGOperation
+	left       Shape
+	right      Shape
+	m          materials.Material
+	shadowless bool
+	id         string
+
+	bounds *BoundingBox
+}
+
+func NewCSG(op CSGOperation, left, right Shape) *CSG {
+	c := &CSG{
+		t:     geom.NewIdentityMatrixX4(),
+		op:    op,
+		left:  left,
+		right: right,
+		id:    uuid.NewString(),
+	}
+	left.SetParent(c)
+	right.SetParent(c)
+	return c
+}
+
+func (c *CSG) Operation() CSGOperation {
+	return c.op
+}
+
+func (c *CSG) Left() Shape {
+	return c.left
+}
+
+func (c *CSG) Right() Shape {
+	return c.right
+}
+
+func (c *CSG) Intersect(ray geom.Ray) *Intersections {
+	return Intersect(ray, c.t, c.LocalIntersect)
+}
+
+// AnyHit needs all intersections of both children to know which are on the surface
+func (c *CSG) AnyHit(r geom.Ray, maxT float64) bool {
+	return AnyHit(c, r, maxT, c.LocalIntersect)
+}
+
+func (c *CSG) LocalIntersect(r geom.Ray) *Intersections {
+	// todo race
+	bounds := c.bounds
+	if bounds == nil {
+		bounds = c.BoundsOf()
+	}
+
+	if !bounds.Intersect(r) {
+		return NewIntersections()
+	}
+
+	xs := NewIntersections()
+	xs.AddFrom(c.left.Intersect(r))
+	xs.AddFrom(c.right.Intersect(r))
+
+	return c.filterIntersections(xs)
+}
+
+// filterIntersections keeps only the intersections that lie on the surface of the combined shape
+func (c *CSG) filterIntersections(xs *Intersections) *Intersections {
+	// begin outside both children
+	inl := false
+	inr := false
+
+	result := make([]Intersection, 0, len(xs.I))
+	for _, i := range xs.I {
+		lhit := includes(c.left, i.O)
+
+		if intersectionAllowed(c.op, lhit, inl, inr) {
+			result = append(result, i)
+		}
+
+		// depending on which object was hit, toggle either inl or inr
+		if lhit {
+			inl = !inl
+		} else {
+			inr = !inr
+		}
+	}
+
+	return NewIntersections(result...)
+}
+
+// intersectionAllowed decides if a hit on one child is part of the combined surface.
+// lhit is true when the left shape was hit, inl and inr track if the hit is inside the left or right shape.
+func intersectionAllowed(op CSGOperation, lhit, inl, inr bool) bool {
+	switch op {
+	case CSGUnion:
+		return (lhit && !inr) || (!lhit && !inl)
+	case CSGIntersection:
+		return (lhit && inr) || (!lhit && inl)
+	case CSGDifference:
+		return (lhit && !inr) || (!lhit && inl)
+	default:
+		return false
+	}
+}
+
+// includes checks if s is o or contains o as a descendant
+func includes(s Shape, o Shape) bool {
+	if s == o {
+		return true
+	}
+	if g, ok := s.(Group); ok {
+		for _, c := range g.GetChildren() {
+			if includes(c, o) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *CSG) BoundsOf() *BoundingBox {
+	b := NewEmptyBoundingBox()
+	b.AddBoundingBoxes(ParentSpaceBoundsOf(c.left), ParentSpaceBoundsOf(c.right))
+	return b
+}
+
+// Divide does nothing to the CSG shape itself but subdivides both children with the midpoint builder
+func (c *CSG) Divide(threshold int) {
+	c.DivideWith(threshold, BVHMidpoint)
+}
+
+func (c *CSG) DivideWith(threshold int, b BVHBuilder) {
+	divideWith(c.left, threshold, b)
+	divideWith(c.right, threshold, b)
+	c.Invalidate()
+}
+
+func (c *CSG) Invalidate() {
+	c.bounds = c.BoundsOf()
+
+	if c.parent != nil {
+		c.parent.Invalidate()
+	}
+}
+
+func (c *CSG) GetTransform() *geom.X4Matrix {
+	return c.t
+}
+
+func (c *CSG) SetTransform(matrix *geom.X4Matrix) {
+	c.t = matrix
+}
+
+func (c *CSG) WorldToObject(p geom.Tuple) geom.Tuple {
+	if c.parent != nil {
+		p = c.parent.WorldToObject(p)
+	}
+	return c.t.Invert().MulTuple(p)
+}
+
+func (c *CSG) NormalToWorld(normal geom.Tuple) geom.Tuple {
+	normal = c.t.Invert().Transpose().MulTuple(normal)
+	normal.C = 0
+	normal = normal.Normalize()
+
+	if c.parent != nil {
+		normal = c.parent.NormalToWorld(normal)
+	}
+	return normal
+}
+
+func (c *CSG) GetParent() Group {
+	return c.parent
+}
+
+func (c *CSG) SetParent(g Group) {
+	c.parent = g
+}
+
+func (c *CSG) GetChildren() []Shape {
+	return []Shape{c.left, c.right}
+}
+
+// AddChild does nothing, a csg has exactly its left and right children
+func (c *CSG) AddChild(_ Shape) {}
+
+func (c *CSG) PartitionChildren() (left, right Group) {
+	// children of a csg are never moved into subgroups
+	return NewGroup(), NewGroup()
+}
+
+func (c *CSG) NormalAt(tuple geom.Tuple, _ Intersection) geom.Tuple {
+	panic("calling me on a csg is a logic error")
+}
+
+func (c *CSG) GetMaterial() materials.Material {
+	if !materials.IsZeroMaterial(c.m) {
+		return c.m
+	}
+	if c.parent != nil {
+		return c.parent.GetMaterial()
+	}
+	return materials.ZeroMaterial()
+}
+
+func (c *CSG) SetMaterial(material materials.Material) {
+	c.m = material
+}
+
+func (c *CSG) Id() string {
+	return c.id
+}
+
+func (c *CSG) GetShadowless() bool {
+	if c.shadowless {
+		return true
+	}
+	if c.parent != nil {
+		return c.parent.GetShadowless()
+	}
+	return false
+}
+
+func (c *CSG) SetShadowless(s bool) {
+	c.shadowless = s
+}
+
+// GetShaded is always true, rays only ever hit the children which answer for themselves
+func (c *CSG) GetShaded() bool {
+	return true
+}
+
+// SetShaded shades or unshades both children
+func (c *CSG) SetShaded(s bool) {
+	c.left.SetShaded(s)
+	c.right.SetShaded(s)
+}
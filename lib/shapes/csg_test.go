@@ -1,66 +1,253 @@
 package shapes
 
-// TODO from bounding box chapter
-//Scenario: A CSG shape has a bounding box that contains its children
-//  Given left ← sphere()
-//    And right ← sphere() with:
-//      | transform | translation(2, 3, 4) |
-//    And shape ← csg("difference", left, right)
-//  When box ← bounds_of(shape)
-//  Then box.min = point(-1, -1, -1)
-//    And box.max = point(3, 4, 5)
-
-// Hint: CSG must find the parent-space bounds of each child object, and then merge them all together into a single bounding box.
-
-//Scenario: Intersecting ray+csg doesn't test children if box is missed
-//  Given left ← test_shape()
-//    And right ← test_shape()
-//    And shape ← csg("difference", left, right)
-//    And r ← ray(point(0, 0, -5), vector(0, 1, 0))
-//  When xs ← intersect(shape, r)
-//  Then left.saved_ray is unset
-//    And right.saved_ray is unset
-//
-//Scenario: Intersecting ray+csg tests children if box is hit
-//  Given left ← test_shape()
-//    And right ← test_shape()
-//    And shape ← csg("difference", left, right)
-//    And r ← ray(point(0, 0, -5), vector(0, 0, 1))
-//  When xs ← intersect(shape, r)
-//  Then left.saved_ray is set
-//    And right.saved_ray is set
-
-//In pseudocode, the local_intersect() function for both the Group and CSG shapes should be modified to look something like this:
-//
-//function local_intersect(shape, ray)
-//  if intersects(bounds_of(shape), ray)
-//    # perform the usual intersection logic
-//    # ...
-//  else
-//    # nothing intersected
-//    return ()
-//  end if
-//end function
-
-//There's just one more shape to which you need to add support for divide(): the CSG shape. In this case the divide() function does nothing to the shape itself, but, propagates the call to the shape's children.
-//
-//The following test demonstrates this with a CSG shape consisting of two groups, each containing two spheres. After calling divide() on the CSG shape, the left and right children will have been split into two subgroups.
-//
-//csg.feature
-//Scenario: Subdividing a CSG shape subdivides its children
-//  Given s1 ← sphere() with:
-//      | transform | translation(-1.5, 0, 0) |
-//    And s2 ← sphere() with:
-//      | transform | translation(1.5, 0, 0) |
-//    And left ← group() of [s1, s2]
-//    And s3 ← sphere() with:
-//      | transform | translation(0, 0, -1.5) |
-//    And s4 ← sphere() with:
-//      | transform | translation(0, 0, 1.5) |
-//    And right ← group() of [s3, s4]
-//    And shape ← csg("difference", left, right)
-//  When divide(shape, 1)
-//  Then left[0] is a group of [s1]
-//    And left[1] is a group of [s2]
-//    And right[0] is a group of [s3]
-//    And right[1] is a group of [s4]
+import (
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func Test_NewCSG(t *testing.T) {
+	s1 := NewSphere()
+	s2 := NewCube()
+
+	c := NewCSG(CSGUnion, s1, s2)
+
+	require.Equal(t, CSGUnion, c.Operation())
+	require.Equal(t, s1, c.Left())
+	require.Equal(t, s2, c.Right())
+	require.Equal(t, c, s1.GetParent())
+	require.Equal(t, c, s2.GetParent())
+}
+
+func Test_CSG_Id(t *testing.T) {
+	c1 := NewCSG(CSGUnion, NewSphere(), NewCube())
+	c2 := NewCSG(CSGUnion, NewSphere(), NewCube())
+
+	require.NotEmpty(t, c1.Id())
+	require.NotEqual(t, c1.Id(), c2.Id())
+}
+
+func Test_CSG_GroupMethods(t *testing.T) {
+	s1 := NewSphere()
+	s2 := NewCube()
+	c := NewCSG(CSGUnion, s1, s2)
+
+	c.AddChild(NewSphere())
+	require.Equal(t, []Shape{s1, s2}, c.GetChildren())
+
+	require.True(t, c.GetShaded())
+	c.SetShaded(false)
+	require.False(t, s1.GetShaded())
+	require.False(t, s2.GetShaded())
+}
+
+func Test_CSG_IntersectionAllowed(t *testing.T) {
+	type args struct {
+		op     CSGOperation
+		lhit   bool
+		inl    bool
+		inr    bool
+		expect bool
+	}
+
+	tests := []args{
+		{CSGUnion, true, true, true, false},
+		{CSGUnion, true, true, false, true},
+		{CSGUnion, true, false, true, false},
+		{CSGUnion, true, false, false, true},
+		{CSGUnion, false, true, true, false},
+		{CSGUnion, false, true, false, false},
+		{CSGUnion, false, false, true, true},
+		{CSGUnion, false, false, false, true},
+		{CSGIntersection, true, true, true, true},
+		{CSGIntersection, true, true, false, false},
+		{CSGIntersection, true, false, true, true},
+		{CSGIntersection, true, false, false, false},
+		{CSGIntersection, false, true, true, true},
+		{CSGIntersection, false, true, false, true},
+		{CSGIntersection, false, false, true, false},
+		{CSGIntersection, false, false, false, false},
+		{CSGDifference, true, true, true, false},
+		{CSGDifference, true, true, false, true},
+		{CSGDifference, true, false, true, false},
+		{CSGDifference, true, false, false, true},
+		{CSGDifference, false, true, true, true},
+		{CSGDifference, false, true, false, true},
+		{CSGDifference, false, false, true, false},
+		{CSGDifference, false, false, false, false},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			require.Equal(t, tt.expect, intersectionAllowed(tt.op, tt.lhit, tt.inl, tt.inr))
+		})
+	}
+}
+
+func Test_CSG_FilterIntersections(t *testing.T) {
+	type args struct {
+		op CSGOperation
+		x0 int
+		x1 int
+	}
+
+	tests := []args{
+		{CSGUnion, 0, 3},
+		{CSGIntersection, 1, 2},
+		{CSGDifference, 0, 1},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			s1 := NewSphere()
+			s2 := NewCube()
+			c := NewCSG(tt.op, s1, s2)
+			xs := NewIntersections(
+				NewIntersection(1, s1),
+				NewIntersection(2, s2),
+				NewIntersection(3, s1),
+				NewIntersection(4, s2),
+			)
+
+			result := c.filterIntersections(xs)
+
+			require.Len(t, result.I, 2)
+			require.Equal(t, xs.I[tt.x0], result.I[0])
+			require.Equal(t, xs.I[tt.x1], result.I[1])
+		})
+	}
+}
+
+func Test_CSG_FilterIntersections_ChildGroups(t *testing.T) {
+	s1 := NewSphere()
+	s2 := NewCube()
+	left := NewGroup()
+	left.AddChild(s1)
+	right := NewGroup()
+	right.AddChild(s2)
+	c := NewCSG(CSGDifference, left, right)
+	xs := NewIntersections(
+		NewIntersection(1, s1),
+		NewIntersection(2, s2),
+		NewIntersection(3, s1),
+		NewIntersection(4, s2),
+	)
+
+	result := c.filterIntersections(xs)
+
+	require.Len(t, result.I, 2)
+	require.Equal(t, xs.I[0], result.I[0])
+	require.Equal(t, xs.I[1], result.I[1])
+}
+
+func Test_CSG_RayMisses(t *testing.T) {
+	c := NewCSG(CSGUnion, NewSphere(), NewCube())
+	r := geom.RayWith(geom.NewPoint(0, 2, -5), geom.NewVector(0, 0, 1))
+
+	xs := c.LocalIntersect(r)
+
+	require.Len(t, xs.I, 0)
+}
+
+func Test_CSG_RayHits(t *testing.T) {
+	s1 := NewSphere()
+	s2 := NewSphere()
+	s2.SetTransform(geom.Translate(0, 0, 0.5))
+	c := NewCSG(CSGUnion, s1, s2)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	xs := c.LocalIntersect(r)
+
+	require.Len(t, xs.I, 2)
+	require.Equal(t, 4.0, xs.I[0].T)
+	require.Equal(t, s1, xs.I[0].O)
+	require.Equal(t, 6.5, xs.I[1].T)
+	require.Equal(t, s2, xs.I[1].O)
+}
+
+func Test_CSG_BoundsContainsChildren(t *testing.T) {
+	left := NewSphere()
+	right := NewSphere()
+	right.SetTransform(geom.Translate(2, 3, 4))
+	c := NewCSG(CSGDifference, left, right)
+
+	box := c.BoundsOf()
+
+	require.Equal(t, geom.NewPoint(-1, -1, -1), box.Min)
+	require.Equal(t, geom.NewPoint(3, 4, 5), box.Max)
+}
+
+func Test_CSG_IntersectNoChildrenIfBoundsMissed(t *testing.T) {
+	left := newTestShape()
+	right := newTestShape()
+	c := NewCSG(CSGDifference, left, right)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 1, 0))
+
+	c.Intersect(r)
+
+	require.Equal(t, geom.Ray{}, left.savedRay)
+	require.Equal(t, geom.Ray{}, right.savedRay)
+}
+
+func Test_CSG_IntersectChildrenIfBoundsHit(t *testing.T) {
+	left := newTestShape()
+	right := newTestShape()
+	c := NewCSG(CSGDifference, left, right)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	c.Intersect(r)
+
+	require.Equal(t, r, left.savedRay)
+	require.Equal(t, r, right.savedRay)
+}
+
+func Test_CSG_Divide(t *testing.T) {
+	s1 := NewSphere()
+	s1.SetTransform(geom.Translate(-1.5, 0, 0))
+	s2 := NewSphere()
+	s2.SetTransform(geom.Translate(1.5, 0, 0))
+	left := NewGroup()
+	left.AddChild(s1)
+	left.AddChild(s2)
+	s3 := NewSphere()
+	s3.SetTransform(geom.Translate(0, 0, -1.5))
+	s4 := NewSphere()
+	s4.SetTransform(geom.Translate(0, 0, 1.5))
+	right := NewGroup()
+	right.AddChild(s3)
+	right.AddChild(s4)
+	c := NewCSG(CSGDifference, left, right)
+
+	c.Divide(1)
+
+	require.Len(t, left.GetChildren(), 2)
+	require.Equal(t, []Shape{s1}, left.GetChildren()[0].(Group).GetChildren())
+	require.Equal(t, []Shape{s2}, left.GetChildren()[1].(Group).GetChildren())
+	require.Len(t, right.GetChildren(), 2)
+	require.Equal(t, []Shape{s3}, right.GetChildren()[0].(Group).GetChildren())
+	require.Equal(t, []Shape{s4}, right.GetChildren()[1].(Group).GetChildren())
+}
+
+func Test_CSG_ChildNormalUsesCSGTransform(t *testing.T) {
+	s1 := NewSphere()
+	s2 := NewSphere()
+	c := NewCSG(CSGUnion, s1, s2)
+	c.SetTransform(geom.Translate(5, 0, 0))
+
+	n := s1.NormalAt(geom.NewPoint(5, 0, -1), Intersection{})
+
+	require.Equal(t, geom.NewVector(0, 0, -1), n)
+}
+
+func Test_CSG_AnyHit(t *testing.T) {
+	// a sphere with a hole cut through along z
+	hole := NewCylinder(-3, 3, true)
+	hole.SetTransform(geom.RotateX(math.Pi / 2).MulX4Matrix(geom.Scale(0.5, 1, 0.5)))
+	c := NewCSG(CSGDifference, NewSphere(), hole)
+
+	require.False(t, c.AnyHit(geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1)), 10))
+	require.True(t, c.AnyHit(geom.RayWith(geom.NewPoint(0, 0.75, -5), geom.NewVector(0, 0, 1)), 10))
+	require.False(t, c.AnyHit(geom.RayWith(geom.NewPoint(0, 0.75, -5), geom.NewVector(0, 0, 1)), 4))
+}
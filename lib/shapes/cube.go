@@ -40,6 +40,10 @@ func (c *Cube) Intersect(r geom.Ray) *Intersections {
 	return Intersect(r, c.t, c.LocalIntersect)
 }
 
+func (c *Cube) AnyHit(r geom.Ray, maxT float64) bool {
+	return AnyHit(c, r, maxT, c.LocalIntersect)
+}
+
 func (c *Cube) LocalIntersect(r geom.Ray) *Intersections {
 	// bounds for a unit cube because we are in local space
 	tMin, tMax := intersectsCube(r, c.BoundsOf())
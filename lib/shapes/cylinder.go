@@ -53,6 +53,10 @@ func (c *Cylinder) Intersect(r geom.Ray) *Intersections {
 	return Intersect(r, c.t, c.LocalIntersect)
 }
 
+func (c *Cylinder) AnyHit(r geom.Ray, maxT float64) bool {
+	return AnyHit(c, r, maxT, c.LocalIntersect)
+}
+
 func (c *Cylinder) LocalIntersect(r geom.Ray) *Intersections {
 	xs := NewIntersections()
 
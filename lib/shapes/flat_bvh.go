@@ -0,0 +1,266 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/geom"
+	"math"
+	"sort"
+)
+
+// FlatBVH is a bounding volume hierarchy compiled from shape trees into flat arrays.
+// Groups are flattened away, their transforms are folded into each primitive and CSG shapes are kept whole.
+// It is read only once built, so it must be compiled again after the shapes change.
+type FlatBVH struct {
+	builder BVHBuilder
+	nodes   []flatNode
+	prims   []flatPrim
+	// shapes with infinite bounds, like planes, are tested against every ray
+	unbounded []flatPrim
+}
+
+// nodes are stored depth first: the first child of an interior node directly follows it
+type flatNode struct {
+	bounds      BoundingBox
+	secondChild int
+	// leaf nodes refer to prims[primStart:primStart+primCount]. interior nodes have no prims
+	primStart int
+	primCount int
+}
+
+type flatPrim struct {
+	s Shape
+	// world space to the space of the shape's parent. nil for top level shapes
+	toParent *geom.X4Matrix
+}
+
+type flatStackEntry struct {
+	node int
+	tMin float64
+}
+
+const flatLeafSize = 4
+
+// NewFlatBVH compiles top level shapes, the ones without a parent, into a FlatBVH split with the builder
+func NewFlatBVH(builder BVHBuilder, shapes ...Shape) *FlatBVH {
+	b := &FlatBVH{builder: builder}
+
+	var prims []flatPrim
+	var boxes []*BoundingBox
+	var collect func(s Shape, toWorld *geom.X4Matrix)
+	collect = func(s Shape, toWorld *geom.X4Matrix) {
+		if g, ok := s.(Group); ok {
+			if _, isCSG := s.(*CSG); !isCSG {
+				childToWorld := g.GetTransform()
+				if toWorld != nil {
+					childToWorld = toWorld.MulX4Matrix(childToWorld)
+				}
+				for _, c := range g.GetChildren() {
+					collect(c, childToWorld)
+				}
+				return
+			}
+		}
+
+		p := flatPrim{s: s}
+		box := ParentSpaceBoundsOf(s)
+		if toWorld != nil {
+			p.toParent = toWorld.Invert()
+			box.Transform(toWorld)
+		}
+		if !isFiniteBox(box) {
+			b.unbounded = append(b.unbounded, p)
+			return
+		}
+		prims = append(prims, p)
+		boxes = append(boxes, box)
+	}
+	for _, s := range shapes {
+		collect(s, nil)
+	}
+
+	if len(prims) > 0 {
+		b.prims = make([]flatPrim, 0, len(prims))
+		b.build(prims, boxes)
+	}
+	return b
+}
+
+func (b *FlatBVH) build(prims []flatPrim, boxes []*BoundingBox) int {
+	idx := len(b.nodes)
+	b.nodes = append(b.nodes, flatNode{})
+
+	bounds := NewEmptyBoundingBox()
+	bounds.AddBoundingBoxes(boxes...)
+
+	if len(prims) <= flatLeafSize {
+		b.nodes[idx] = flatNode{
+			bounds:    *bounds,
+			primStart: len(b.prims),
+			primCount: len(prims),
+		}
+		b.prims = append(b.prims, prims...)
+		return idx
+	}
+
+	order, split := b.builder.split(boxes)
+	sortedPrims := make([]flatPrim, len(prims))
+	sortedBoxes := make([]*BoundingBox, len(boxes))
+	for k, i := range order {
+		sortedPrims[k] = prims[i]
+		sortedBoxes[k] = boxes[i]
+	}
+
+	b.build(sortedPrims[:split], sortedBoxes[:split])
+	second := b.build(sortedPrims[split:], sortedBoxes[split:])
+	b.nodes[idx] = flatNode{
+		bounds:      *bounds,
+		secondChild: second,
+	}
+	return idx
+}
+
+// Intersect returns every intersection of the ray with the compiled shapes, sorted by t
+func (b *FlatBVH) Intersect(r geom.Ray) *Intersections {
+	var xs SortableIntersections
+	for _, p := range b.unbounded {
+		xs = append(xs, p.intersect(r).I...)
+	}
+
+	b.traverse(r, math.Inf(-1), math.Inf(1), func(prims []flatPrim) float64 {
+		for _, p := range prims {
+			xs = append(xs, p.intersect(r).I...)
+		}
+		return math.Inf(1)
+	})
+
+	sort.Sort(xs)
+	if xs == nil {
+		xs = SortableIntersections{}
+	}
+	return &Intersections{I: xs}
+}
+
+// ClosestHit returns the nearest intersection in front of the ray origin.
+// nodes farther away than the closest hit found so far are never visited.
+func (b *FlatBVH) ClosestHit(r geom.Ray) (Intersection, bool) {
+	best := Intersection{T: math.Inf(1)}
+	found := false
+	consider := func(p flatPrim) {
+		if h, ok := p.intersect(r).Hit(); ok && h.T < best.T {
+			best = h
+			found = true
+		}
+	}
+
+	for _, p := range b.unbounded {
+		consider(p)
+	}
+
+	b.traverse(r, 0, best.T, func(prims []flatPrim) float64 {
+		for _, p := range prims {
+			consider(p)
+		}
+		return best.T
+	})
+
+	return best, found
+}
+
+// AnyHit reports if the ray hits anything casting shadows between 0 and maxT, stopping at the first one found
+func (b *FlatBVH) AnyHit(r geom.Ray, maxT float64) bool {
+	for _, p := range b.unbounded {
+		if p.anyHit(r, maxT) {
+			return true
+		}
+	}
+
+	found := false
+	b.traverse(r, 0, maxT, func(prims []flatPrim) float64 {
+		for _, p := range prims {
+			if p.anyHit(r, maxT) {
+				found = true
+				// nothing is entered before negative infinity, so the rest of the stack is skipped
+				return math.Inf(-1)
+			}
+		}
+		return maxT
+	})
+	return found
+}
+
+// traverse calls visit for each leaf the ray passes through between minT and maxT, near leaves first.
+// visit returns the new maxT so that traversal can stop early.
+func (b *FlatBVH) traverse(r geom.Ray, minT, maxT float64, visit func(prims []flatPrim) float64) {
+	if len(b.nodes) == 0 {
+		return
+	}
+
+	tMin, ok := b.enter(r, 0, minT, maxT)
+	if !ok {
+		return
+	}
+
+	stack := make([]flatStackEntry, 0, 64)
+	stack = append(stack, flatStackEntry{node: 0, tMin: tMin})
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if e.tMin > maxT {
+			continue
+		}
+
+		n := &b.nodes[e.node]
+		if n.primCount > 0 {
+			maxT = visit(b.prims[n.primStart : n.primStart+n.primCount])
+			continue
+		}
+
+		first, second := e.node+1, n.secondChild
+		tFirst, okFirst := b.enter(r, first, minT, maxT)
+		tSecond, okSecond := b.enter(r, second, minT, maxT)
+		switch {
+		case okFirst && okSecond:
+			// push the far child first so the near child is visited next
+			if tSecond < tFirst {
+				first, second = second, first
+				tFirst, tSecond = tSecond, tFirst
+			}
+			stack = append(stack, flatStackEntry{node: second, tMin: tSecond}, flatStackEntry{node: first, tMin: tFirst})
+		case okFirst:
+			stack = append(stack, flatStackEntry{node: first, tMin: tFirst})
+		case okSecond:
+			stack = append(stack, flatStackEntry{node: second, tMin: tSecond})
+		}
+	}
+}
+
+// enter returns where the ray enters the node bounds, if it overlaps minT to maxT at all
+func (b *FlatBVH) enter(r geom.Ray, node int, minT, maxT float64) (float64, bool) {
+	tMin, tMax := intersectsCube(r, &b.nodes[node].bounds)
+	if tMin > tMax || tMax < minT || tMin > maxT {
+		return 0, false
+	}
+	return tMin, true
+}
+
+func (p flatPrim) intersect(r geom.Ray) *Intersections {
+	if p.toParent != nil {
+		r = r.Transform(p.toParent)
+	}
+	return p.s.Intersect(r)
+}
+
+func (p flatPrim) anyHit(r geom.Ray, maxT float64) bool {
+	if p.toParent != nil {
+		r = r.Transform(p.toParent)
+	}
+	return p.s.AnyHit(r, maxT)
+}
+
+func isFiniteBox(b *BoundingBox) bool {
+	for _, v := range []float64{b.Min.X, b.Min.Y, b.Min.Z, b.Max.X, b.Max.Y, b.Max.Z} {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,207 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func flatBVHTestScene() []Shape {
+	grid := NewGroup()
+	for x := -4; x <= 4; x++ {
+		for z := -4; z <= 4; z++ {
+			s := NewSphere()
+			s.SetTransform(geom.Translate(float64(x)*2, 0, float64(z)*2).MulX4Matrix(geom.Scale(0.5, 0.5+float64(x+4)*0.05, 0.5)))
+			grid.AddChild(s)
+		}
+	}
+	grid.SetTransform(geom.Translate(0, 1, 0).MulX4Matrix(geom.RotateY(math.Pi / 7)))
+
+	inner := NewGroup()
+	inner.SetTransform(geom.Scale(2, 2, 2))
+	c := NewCube()
+	c.SetTransform(geom.Translate(0, 3, 0))
+	inner.AddChild(c)
+	grid.AddChild(inner)
+	grid.Divide(4)
+
+	csg := NewCSG(CSGDifference, NewCube(), NewSphere())
+	csg.SetTransform(geom.Translate(12, 1, 0))
+
+	p := NewPlane()
+	p.SetTransform(geom.Translate(0, -1, 0))
+
+	return []Shape{grid, csg, p}
+}
+
+func flatBVHTestRays() []geom.Ray {
+	return []geom.Ray{
+		geom.RayWith(geom.NewPoint(0, 10, 0), geom.NewVector(0, -1, 0)),
+		geom.RayWith(geom.NewPoint(-20, 1, 0.3), geom.NewVector(1, 0, 0)),
+		geom.RayWith(geom.NewPoint(-20, 5, -20), geom.NewVector(1, -0.2, 1)),
+		geom.RayWith(geom.NewPoint(12, 1, -10), geom.NewVector(0, 0, 1)),
+		geom.RayWith(geom.NewPoint(0, 7, -10), geom.NewVector(0, 0, 1)),
+		geom.RayWith(geom.NewPoint(0, 20, 0), geom.NewVector(0, 1, 0)),
+		geom.RayWith(geom.NewPoint(3.1, 1, 2.2), geom.NewVector(-0.3, 0.1, 1)),
+	}
+}
+
+func Test_FlatBVH_IntersectMatchesShapes(t *testing.T) {
+	shapes := flatBVHTestScene()
+	for _, builder := range []BVHBuilder{BVHMidpoint, BVHSAH} {
+		bvh := NewFlatBVH(builder, shapes...)
+
+		for ti, r := range flatBVHTestRays() {
+			t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+				expect := NewIntersections()
+				for _, s := range shapes {
+					expect.AddFrom(s.Intersect(r))
+				}
+
+				xs := bvh.Intersect(r)
+
+				require.Len(t, xs.I, len(expect.I))
+				for i := range expect.I {
+					require.InDelta(t, expect.I[i].T, xs.I[i].T, 1e-9)
+					require.Equal(t, expect.I[i].O.Id(), xs.I[i].O.Id())
+				}
+			})
+		}
+	}
+}
+
+func Test_FlatBVH_ClosestHitMatchesShapes(t *testing.T) {
+	shapes := flatBVHTestScene()
+	for _, builder := range []BVHBuilder{BVHMidpoint, BVHSAH} {
+		bvh := NewFlatBVH(builder, shapes...)
+
+		for ti, r := range flatBVHTestRays() {
+			t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+				expect := NewIntersections()
+				for _, s := range shapes {
+					expect.AddFrom(s.Intersect(r))
+				}
+				expectHit, expectOk := expect.Hit()
+
+				h, ok := bvh.ClosestHit(r)
+
+				require.Equal(t, expectOk, ok)
+				if ok {
+					require.InDelta(t, expectHit.T, h.T, 1e-9)
+					require.Equal(t, expectHit.O.Id(), h.O.Id())
+				}
+			})
+		}
+	}
+}
+
+func Test_FlatBVH_ClosestHitVisitsAllWithoutHits(t *testing.T) {
+	g := NewGroup()
+	var children []*testShape
+	for i := 0; i < 32; i++ {
+		s := newTestShape()
+		s.SetTransform(geom.Translate(0, 0, float64(i)*3))
+		children = append(children, s)
+		g.AddChild(s)
+	}
+	bvh := NewFlatBVH(BVHMidpoint, g)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	_, ok := bvh.ClosestHit(r)
+
+	// test shapes record the ray but never report intersections
+	require.False(t, ok)
+	require.NotEqual(t, geom.Ray{}, children[0].savedRay)
+	require.NotEqual(t, geom.Ray{}, children[31].savedRay)
+}
+
+func Test_FlatBVH_NodesPruned(t *testing.T) {
+	g := NewGroup()
+	for i := 0; i < 32; i++ {
+		s := NewSphere()
+		s.SetTransform(geom.Translate(0, 0, float64(i)*3))
+		g.AddChild(s)
+	}
+	far := newTestShape()
+	far.SetTransform(geom.Translate(0, 0, 200))
+	g.AddChild(far)
+	bvh := NewFlatBVH(BVHMidpoint, g)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	h, ok := bvh.ClosestHit(r)
+
+	require.True(t, ok)
+	require.Equal(t, 4.0, h.T)
+	// the far node is entered after the closest hit so it is never tested
+	require.Equal(t, geom.Ray{}, far.savedRay)
+}
+
+func Test_FlatBVH_Empty(t *testing.T) {
+	bvh := NewFlatBVH(BVHMidpoint)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	require.Len(t, bvh.Intersect(r).I, 0)
+	_, ok := bvh.ClosestHit(r)
+	require.False(t, ok)
+}
+
+func Test_FlatBVH_AnyHitMatchesShapes(t *testing.T) {
+	shapes := flatBVHTestScene()
+	for _, builder := range []BVHBuilder{BVHMidpoint, BVHSAH} {
+		bvh := NewFlatBVH(builder, shapes...)
+
+		for ti, r := range flatBVHTestRays() {
+			for _, maxT := range []float64{1, 5, 15, math.Inf(1)} {
+				t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+					expect := false
+					for _, s := range shapes {
+						expect = expect || s.AnyHit(r, maxT)
+					}
+
+					require.Equal(t, expect, bvh.AnyHit(r, maxT))
+				})
+			}
+		}
+	}
+}
+
+func Test_FlatBVH_AnyHitSkipsShadowless(t *testing.T) {
+	g := NewGroup()
+	s := NewSphere()
+	g.AddChild(s)
+	g.SetShadowless(true)
+	bvh := NewFlatBVH(BVHMidpoint, g)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	require.False(t, bvh.AnyHit(r, 10))
+	_, ok := bvh.ClosestHit(r)
+	require.True(t, ok)
+}
+
+func Test_FlatBVH_BuilderLayout(t *testing.T) {
+	// one big sphere next to a row of small ones
+	g := NewGroup()
+	big := NewSphere()
+	big.SetTransform(geom.Scale(10, 10, 10))
+	g.AddChild(big)
+	for i := 1; i <= 15; i++ {
+		s := NewSphere()
+		s.SetTransform(geom.Translate(float64(i), 0, 0).MulX4Matrix(geom.Scale(0.2, 0.2, 0.2)))
+		g.AddChild(s)
+	}
+
+	midpoint := NewFlatBVH(BVHMidpoint, g)
+	sah := NewFlatBVH(BVHSAH, g)
+
+	// the midpoint of the bounds keeps the big sphere with the first two small ones,
+	// the surface area heuristic only with the one it overlaps most
+	require.Equal(t, 3, midpoint.nodes[1].primCount)
+	require.Equal(t, 2, sah.nodes[1].primCount)
+	require.NotEqual(t, midpoint.nodes, sah.nodes)
+
+	r := geom.RayWith(geom.NewPoint(5, 0, -20), geom.NewVector(0, 0, 1))
+	require.Equal(t, g.Intersect(r).I, midpoint.Intersect(r).I)
+	require.Equal(t, g.Intersect(r).I, sah.Intersect(r).I)
+}
@@ -10,6 +10,7 @@ type Group interface {
 	GetChildren() []Shape
 	AddChild(s Shape)
 	PartitionChildren() (left, right Group)
+	DivideWith(threshold int, b BVHBuilder)
 }
 
 type group struct {
@@ -21,6 +22,8 @@ type group struct {
 	id         string
 
 	bounds *BoundingBox
+	// children were split into subgroups and none were added since
+	divided bool
 }
 
 func NewGroup() Group {
@@ -55,6 +58,26 @@ func (g *group) LocalIntersect(r geom.Ray) *Intersections {
 	return xs
 }
 
+func (g *group) AnyHit(r geom.Ray, maxT float64) bool {
+	lr := r.Transform(g.t.Invert())
+
+	// todo race
+	bounds := g.bounds
+	if bounds == nil {
+		bounds = g.BoundsOf()
+	}
+
+	if !bounds.Intersect(lr) {
+		return false
+	}
+	for _, s := range g.children {
+		if s.AnyHit(lr, maxT) {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *group) BoundsOf() *BoundingBox {
 	b := NewEmptyBoundingBox()
 	for _, c := range g.children {
@@ -64,21 +87,45 @@ func (g *group) BoundsOf() *BoundingBox {
 	return b
 }
 
+// Divide splits the children with the midpoint builder
 func (g *group) Divide(threshold int) {
+	g.DivideWith(threshold, BVHMidpoint)
+}
+
+// DivideWith splits the children into subgroups until fewer than threshold are left in each.
+// a group is split again only after children were added to it, so its subgroups are not nested deeper
+func (g *group) DivideWith(threshold int, b BVHBuilder) {
+	if g.divided {
+		return
+	}
 	g.Invalidate()
-	if threshold <= len(g.children) {
-		left, right := g.PartitionChildren()
+	if threshold <= len(g.children) && (b != BVHSAH || len(g.children) > 1) {
+		var left, right Group
+		if b == BVHSAH {
+			left, right = partitionSAH(g)
+		} else {
+			left, right = g.PartitionChildren()
+		}
 		if len(left.GetChildren()) > 0 {
 			g.AddChild(left)
 		}
 		if len(right.GetChildren()) > 0 {
 			g.AddChild(right)
 		}
+		g.divided = true
 	}
 
 	for _, child := range g.children {
-		child.Divide(threshold)
+		divideWith(child, threshold, b)
+	}
+}
+
+func divideWith(s Shape, threshold int, b BVHBuilder) {
+	if g, ok := s.(Group); ok {
+		g.DivideWith(threshold, b)
+		return
 	}
+	s.Divide(threshold)
 }
 
 func (g *group) Invalidate() {
@@ -130,6 +177,7 @@ func (g *group) GetChildren() []Shape {
 func (g *group) AddChild(s Shape) {
 	s.SetParent(g)
 	g.children = append(g.children, s)
+	g.divided = false
 }
 
 func (g *group) PartitionChildren() (left, right Group) {
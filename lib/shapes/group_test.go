@@ -215,6 +215,13 @@ func Test_Group_Divide(t *testing.T) {
 	ggr := gg.GetChildren()[1].(Group)
 	require.Equal(t, []Shape{s1}, ggl.GetChildren())
 	require.Equal(t, []Shape{s2}, ggr.GetChildren())
+
+	// dividing again does not nest the subgroups any deeper
+	g.Divide(1)
+	g.DivideWith(1, BVHSAH)
+	require.Len(t, g.GetChildren(), 2)
+	require.Equal(t, gg, g.GetChildren()[1])
+	require.Equal(t, []Shape{s1}, ggl.GetChildren())
 }
 
 func Test_Group_Divide_TooFewChildren(t *testing.T) {
@@ -252,3 +259,71 @@ func Test_Group_Divide_TooFewChildren(t *testing.T) {
 	require.Equal(t, []Shape{s1}, sgcl.GetChildren())
 	require.Equal(t, []Shape{s2, s3}, sgcr.GetChildren())
 }
+
+func Test_Group_AnyHit(t *testing.T) {
+	s1 := NewSphere()
+	s1.SetTransform(geom.Translate(0, 0, -3))
+	s2 := NewSphere()
+	s2.SetTransform(geom.Translate(5, 0, 0))
+	g := NewGroup()
+	g.SetTransform(geom.Scale(2, 2, 2))
+	g.AddChild(s1)
+	g.AddChild(s2)
+	r := geom.RayWith(geom.NewPoint(10, 0, -10), geom.NewVector(0, 0, 1))
+
+	require.True(t, g.AnyHit(r, 20))
+	require.False(t, g.AnyHit(r, 5))
+
+	g.SetShadowless(true)
+	require.False(t, g.AnyHit(r, 20))
+}
+
+func Test_Group_AnyHit_BoundsMissed(t *testing.T) {
+	child := newTestShape()
+	g := NewGroup()
+	g.AddChild(child)
+
+	g.AnyHit(geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 1, 0)), 10)
+	require.Equal(t, geom.Ray{}, child.savedRay)
+
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+	g.AnyHit(r, 10)
+	require.Equal(t, r, child.savedRay)
+}
+
+func Test_WorldBoundsOf_NestedGroups(t *testing.T) {
+	s := NewSphere()
+	s.SetTransform(geom.Translate(1, 0, 0))
+	inner := NewGroup()
+	inner.SetTransform(geom.Scale(2, 2, 2))
+	inner.AddChild(s)
+	outer := NewGroup()
+	outer.SetTransform(geom.Translate(0, 5, 0))
+	outer.AddChild(inner)
+
+	box := WorldBoundsOf(s)
+
+	require.Equal(t, geom.NewPoint(0, 3, -2), box.Min)
+	require.Equal(t, geom.NewPoint(4, 7, 2), box.Max)
+}
+
+func Test_Group_Divide_AfterAddChild(t *testing.T) {
+	s1 := NewSphere()
+	s1.SetTransform(geom.Translate(-2, 0, 0))
+	s2 := NewSphere()
+	s2.SetTransform(geom.Translate(2, 0, 0))
+
+	g := NewGroup()
+	g.AddChild(s1)
+	g.AddChild(s2)
+	g.Divide(1)
+	require.Len(t, g.GetChildren(), 2)
+
+	// the added child is split into a subgroup on the next divide
+	s3 := NewSphere()
+	s3.SetTransform(geom.Translate(20, 0, 0))
+	g.AddChild(s3)
+	g.Divide(1)
+
+	require.NotContains(t, g.GetChildren(), s3)
+}
@@ -38,6 +38,12 @@ type IntersectionComputed struct {
 	Reflectv   geom.Tuple
 	N1         float64
 	N2         float64
+	// Medium is the shape the ray travelled through to reach the hit, nil outside of everything
+	Medium Shape
+	// NextMedium is the shape a refracted ray continues into, nil outside of everything
+	NextMedium Shape
+	// Distance is how far the ray travelled to reach the hit
+	Distance float64
 }
 
 func (i Intersection) Compute(r geom.Ray, xs *Intersections) IntersectionComputed {
@@ -46,6 +52,7 @@ func (i Intersection) Compute(r geom.Ray, xs *Intersections) IntersectionCompute
 	c.Object = i.O
 	c.point = r.Position(c.t)
 	c.Eyev = r.Direction.Neg()
+	c.Distance = c.t * r.Direction.Mag()
 
 	if len(xs.I) == 0 {
 		c.Normalv = c.Object.NormalAt(c.point, Intersection{})
@@ -74,7 +81,8 @@ func (i Intersection) Compute(r geom.Ray, xs *Intersections) IntersectionCompute
 			if len(containers) == 0 {
 				c.N1 = 1
 			} else {
-				c.N1 = containers[len(containers)-1].GetMaterial().RefractiveIndex
+				c.Medium = containers[len(containers)-1]
+				c.N1 = c.Medium.GetMaterial().RefractiveIndex
 			}
 		}
 
@@ -94,7 +102,8 @@ func (i Intersection) Compute(r geom.Ray, xs *Intersections) IntersectionCompute
 			if len(containers) == 0 {
 				c.N2 = 1
 			} else {
-				c.N2 = containers[len(containers)-1].GetMaterial().RefractiveIndex
+				c.NextMedium = containers[len(containers)-1]
+				c.N2 = c.NextMedium.GetMaterial().RefractiveIndex
 			}
 			break
 		}
@@ -206,6 +206,28 @@ func Test_Compute_RefractionScene(t *testing.T) {
 	}
 }
 
+func Test_Compute_Media(t *testing.T) {
+	outer := NewSphere()
+	outer.SetTransform(geom.Scale(2, 2, 2))
+	inner := NewSphere()
+	r := geom.RayWith(geom.NewPoint(0, 0, -4), geom.NewVector(0, 0, 2))
+	xs := NewIntersections(
+		NewIntersection(1, outer),
+		NewIntersection(1.5, inner),
+		NewIntersection(2.5, inner),
+		NewIntersection(3, outer),
+	)
+
+	expected := []Shape{nil, outer, inner, outer, nil}
+	for i := 0; i < len(xs.I); i++ {
+		c := xs.I[i].Compute(r, xs)
+		assert.Equal(t, expected[i], c.Medium)
+		assert.Equal(t, expected[i+1], c.NextMedium)
+		// measured along the ray, not in units of its direction
+		assert.Equal(t, xs.I[i].T*2, c.Distance)
+	}
+}
+
 func Test_Schlick_TotalInternalReflection(t *testing.T) {
 	s := NewSphere()
 	s.SetMaterial(materials.NewGlassMaterial())
@@ -9,21 +9,46 @@ import (
 
 type Light interface {
 	GetIntensity() colors.Color
-	GetSamples() []geom.Tuple
-	// todo unify Intensity functions
+	// SamplesFrom are the points of the light seen from p. each is shaded and has its own shadow ray
+	SamplesFrom(p geom.Tuple) []LightSample
+	// Falloff scales the light arriving at p, from 0 to 1
+	Falloff(p geom.Tuple) float64
+}
+
+// LightSample is the way from a shaded point to one point of a light
+type LightSample struct {
+	// Direction is normalized and points toward the light
+	Direction geom.Tuple
+	// Distance is how far a shadow ray must go, infinite for directional lights
+	Distance float64
+}
+
+func sampleToward(p, lightPosition geom.Tuple) LightSample {
+	v := lightPosition.Sub(p)
+	return LightSample{Direction: v.Normalize(), Distance: v.Mag()}
 }
 
 type PointLight struct {
-	Position  geom.Tuple
-	Intensity colors.Color
+	Position    geom.Tuple
+	Intensity   colors.Color
+	Attenuation Attenuation
 }
 
 func (p PointLight) GetIntensity() colors.Color {
 	return p.Intensity
 }
 
-func (p PointLight) GetSamples() []geom.Tuple {
-	return []geom.Tuple{p.Position}
+func (p PointLight) SamplesFrom(pt geom.Tuple) []LightSample {
+	return []LightSample{sampleToward(pt, p.Position)}
+}
+
+func (p PointLight) Falloff(pt geom.Tuple) float64 {
+	return p.Attenuation.At(pt.Sub(p.Position).Mag())
+}
+
+func (p PointLight) WithAttenuation(a Attenuation) PointLight {
+	p.Attenuation = a
+	return p
 }
 
 func NewPointLight(p geom.Tuple, i colors.Color) PointLight {
@@ -43,23 +68,34 @@ type AreaLight struct {
 	Intensity colors.Color
 	Seq       Sequence
 	Center    geom.Tuple
+	// attenuation is measured from the center
+	Attenuation Attenuation
 }
 
 func (a AreaLight) GetIntensity() colors.Color {
 	return a.Intensity
 }
 
-func (a AreaLight) GetSamples() []geom.Tuple {
-	s := make([]geom.Tuple, 0, a.Samples)
-	for i := 0; i < a.USteps; i++ {
-		for j := 0; j < a.VSteps; j++ {
-			s = append(s, a.PointOnLight(i, j))
+func (a AreaLight) SamplesFrom(p geom.Tuple) []LightSample {
+	s := make([]LightSample, 0, a.Samples)
+	for v := 0; v < a.VSteps; v++ {
+		for u := 0; u < a.USteps; u++ {
+			s = append(s, sampleToward(p, a.PointOnLight(u, v)))
 		}
 	}
 
 	return s
 }
 
+func (a AreaLight) Falloff(p geom.Tuple) float64 {
+	return a.Attenuation.At(p.Sub(a.Center).Mag())
+}
+
+func (a AreaLight) WithAttenuation(at Attenuation) AreaLight {
+	a.Attenuation = at
+	return a
+}
+
 func NewAreaLight(corner geom.Tuple, uVec geom.Tuple, uSteps int, vVec geom.Tuple, vSteps int, intensity colors.Color, seq Sequence) AreaLight {
 	if seq == nil {
 		seq = NewRandomSequence()
@@ -83,6 +119,122 @@ func (a AreaLight) PointOnLight(u, v int) geom.Tuple {
 	return a.Corner.Add(a.UVec.Mul(float64(u) + uJit)).Add(a.VVec.Mul(float64(v) + vJit))
 }
 
+// SpotLight shines from a point in a cone, fully inside InnerAngle and fading out until OuterAngle.
+// angles are measured from the direction to the cone edge, in radians
+type SpotLight struct {
+	Position    geom.Tuple
+	Direction   geom.Tuple
+	InnerAngle  float64
+	OuterAngle  float64
+	Intensity   colors.Color
+	Attenuation Attenuation
+}
+
+func NewSpotLight(position geom.Tuple, pointingAt geom.Tuple, innerAngle, outerAngle float64, intensity colors.Color) SpotLight {
+	return SpotLight{
+		Position:   position,
+		Direction:  pointingAt.Sub(position).Normalize(),
+		InnerAngle: innerAngle,
+		OuterAngle: math.Max(innerAngle, outerAngle),
+		Intensity:  intensity,
+	}
+}
+
+func (s SpotLight) GetIntensity() colors.Color {
+	return s.Intensity
+}
+
+func (s SpotLight) SamplesFrom(p geom.Tuple) []LightSample {
+	return []LightSample{sampleToward(p, s.Position)}
+}
+
+func (s SpotLight) Falloff(p geom.Tuple) float64 {
+	v := p.Sub(s.Position)
+	return s.cone(v.Normalize().Dot(s.Direction)) * s.Attenuation.At(v.Mag())
+}
+
+func (s SpotLight) cone(cosAngle float64) float64 {
+	cosInner := math.Cos(s.InnerAngle)
+	cosOuter := math.Cos(s.OuterAngle)
+	if cosAngle >= cosInner {
+		return 1
+	}
+	if cosAngle <= cosOuter {
+		return 0
+	}
+	// smoothstep across the penumbra
+	t := (cosAngle - cosOuter) / (cosInner - cosOuter)
+	return t * t * (3 - 2*t)
+}
+
+func (s SpotLight) WithAttenuation(a Attenuation) SpotLight {
+	s.Attenuation = a
+	return s
+}
+
+// DirectionalLight is infinitely far away, like the sun. every point sees it from the same direction.
+// with an angular diameter the light is a disk in the sky and shadows get soft edges
+type DirectionalLight struct {
+	// Direction the light travels in, normalized
+	Direction       geom.Tuple
+	AngularDiameter float64
+	Samples         int
+	Intensity       colors.Color
+	Seq             Sequence
+}
+
+// NewDirectionalLight makes a light shining along direction. angularDiameter is in radians, 0 for hard shadows,
+// and samples is how many shadow rays a wider light casts
+func NewDirectionalLight(direction geom.Tuple, angularDiameter float64, samples int, intensity colors.Color, seq Sequence) DirectionalLight {
+	if seq == nil {
+		seq = NewRandomSequence()
+	}
+	if samples < 1 || angularDiameter <= 0 {
+		samples = 1
+	}
+	return DirectionalLight{
+		Direction:       direction.Normalize(),
+		AngularDiameter: math.Max(0, angularDiameter),
+		Samples:         samples,
+		Intensity:       intensity,
+		Seq:             seq,
+	}
+}
+
+func (d DirectionalLight) GetIntensity() colors.Color {
+	return d.Intensity
+}
+
+func (d DirectionalLight) SamplesFrom(geom.Tuple) []LightSample {
+	toLight := d.Direction.Neg()
+	if d.AngularDiameter <= 0 {
+		return []LightSample{{Direction: toLight, Distance: math.Inf(1)}}
+	}
+
+	// uniform directions within the cone the light disk covers
+	cosMax := math.Cos(d.AngularDiameter / 2)
+	t, b := geom.OrthonormalBasis(toLight)
+	s := make([]LightSample, 0, d.Samples)
+	for i := 0; i < d.Samples; i++ {
+		local := geom.SampleCone(d.Seq.Next(), d.Seq.Next(), cosMax)
+		s = append(s, LightSample{Direction: geom.ToBasis(local, t, b, toLight), Distance: math.Inf(1)})
+	}
+	return s
+}
+
+// Falloff is always 1, the light is too far away for the distance to change
+func (d DirectionalLight) Falloff(geom.Tuple) float64 {
+	return 1
+}
+
+// SurfaceColor is the color of the material pattern at world point p, or the plain material color
+func SurfaceColor(m materials.Material, s Shape, p geom.Tuple) colors.Color {
+	if m.Pattern != nil {
+		return m.Pattern.ColorAtShape(s.WorldToObject, p)
+	}
+	return m.Color
+}
+
 // lighting calculates Phong lighting
 func Lighting(m materials.Material, s Shape, l Light, p geom.Tuple, eyev geom.Tuple, nv geom.Tuple, intensity float64) colors.Color {
 	if !s.GetShaded() {
@@ -90,26 +242,23 @@ func Lighting(m materials.Material, s Shape, l Light, p geom.Tuple, eyev geom.Tu
 		intensity = 1.0
 	}
 
-	var materialColor colors.Color
-	if m.Pattern != nil {
-		materialColor = m.Pattern.ColorAtShape(s.WorldToObject, p)
-	} else {
-		materialColor = m.Color
-	}
-
-	effectiveColor := materialColor.Mul(l.GetIntensity())
+	effectiveColor := SurfaceColor(m, s, p).Mul(l.GetIntensity())
 	ambient := effectiveColor.MulBy(m.Ambient)
 	if intensity == 0 {
 		// object is in the shade, no other lighting to calculate
 		return ambient
 	}
 
+	if m.Model == materials.ModelMetallicRoughness {
+		return ambient.Add(microfacetLighting(m, s, l, p, eyev, nv).MulBy(intensity * l.Falloff(p)))
+	}
+
 	sum := colors.Black()
 
 	numSamples := 0
-	for _, sample := range l.GetSamples() {
+	for _, sample := range l.SamplesFrom(p) {
 		numSamples++
-		lightv := sample.Sub(p).Normalize()
+		lightv := sample.Direction
 		lightDotNormal := lightv.Dot(nv)
 
 		diffuse := colors.NewColor(0, 0, 0)
@@ -133,5 +282,22 @@ func Lighting(m materials.Material, s Shape, l Light, p geom.Tuple, eyev geom.Tu
 		sum = sum.Add(specular)
 	}
 
-	return ambient.Add(sum.MulBy(intensity / float64(numSamples))) // todo or intensity multiply all?
+	return ambient.Add(sum.MulBy(intensity * l.Falloff(p) / float64(numSamples))) // todo or intensity multiply all?
+}
+
+// microfacetLighting is the light from l reflected by a metallic roughness material, before shadows and falloff.
+// scaled by pi so a white rough dielectric is as bright as a phong material with Diffuse 1
+func microfacetLighting(m materials.Material, s Shape, l Light, p geom.Tuple, eyev geom.Tuple, nv geom.Tuple) colors.Color {
+	bsdf := newMicrofacetBSDF(m, SurfaceColor(m, s, p), nv, eyev)
+	sum := colors.Black()
+	samples := l.SamplesFrom(p)
+	for _, sample := range samples {
+		if cos := sample.Direction.Dot(nv); cos > 0 {
+			sum = sum.Add(bsdf.Eval(sample.Direction).MulBy(math.Pi * cos))
+		}
+	}
+	if len(samples) == 0 {
+		return colors.Black()
+	}
+	return sum.Mul(l.GetIntensity()).MulBy(1 / float64(len(samples)))
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/robkau/go-raytrace/lib/geom"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"math"
 	"strconv"
 	"testing"
 )
@@ -63,3 +64,78 @@ func Test_AreaLight_Points(t *testing.T) {
 		})
 	}
 }
+
+func Test_PointLight_SamplesFrom(t *testing.T) {
+	l := NewPointLight(geom.NewPoint(0, 10, 0), colors.White())
+
+	s := l.SamplesFrom(geom.NewPoint(0, 2, 0))
+
+	require.Len(t, s, 1)
+	require.Equal(t, geom.NewVector(0, 1, 0), s[0].Direction)
+	require.Equal(t, 8.0, s[0].Distance)
+	require.Equal(t, 1.0, l.Falloff(geom.ZeroPoint()))
+}
+
+func Test_AreaLight_SamplesFrom(t *testing.T) {
+	light := NewAreaLight(geom.NewPoint(-1, 5, -1), geom.NewVector(2, 0, 0), 2, geom.NewVector(0, 0, 2), 3, colors.White(), NewJitterSequence(0.5))
+
+	s := light.SamplesFrom(geom.ZeroPoint())
+
+	require.Len(t, s, 6)
+	for _, sample := range s {
+		require.True(t, sample.Direction.Y > 0)
+		require.InDelta(t, 1, sample.Direction.Mag(), 1e-9)
+	}
+}
+
+func Test_SpotLight_Falloff(t *testing.T) {
+	l := NewSpotLight(geom.NewPoint(0, 10, 0), geom.ZeroPoint(), math.Pi/8, math.Pi/4, colors.White())
+
+	type args struct {
+		p      geom.Tuple
+		expect float64
+	}
+
+	tests := []args{
+		{geom.ZeroPoint(), 1},
+		{geom.NewPoint(10*math.Tan(math.Pi/10), 0, 0), 1},
+		{geom.NewPoint(0, 0, 10*math.Tan(math.Pi/3)), 0},
+		{geom.NewPoint(0, 20, 0), 0},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			require.InDelta(t, tt.expect, l.Falloff(tt.p), 1e-9)
+		})
+	}
+
+	// the penumbra fades smoothly
+	previous := 1.0
+	for a := math.Pi / 8; a <= math.Pi/4; a += math.Pi / 80 {
+		f := l.Falloff(geom.NewPoint(10*math.Tan(a), 0, 0))
+		require.True(t, f <= previous)
+		previous = f
+	}
+}
+
+func Test_DirectionalLight_SamplesFrom(t *testing.T) {
+	hard := NewDirectionalLight(geom.NewVector(0, -2, 0), 0, 16, colors.White(), nil)
+
+	s := hard.SamplesFrom(geom.NewPoint(3, 4, 5))
+
+	require.Equal(t, 1, hard.Samples)
+	require.Len(t, s, 1)
+	require.Equal(t, geom.NewVector(0, 1, 0), s[0].Direction)
+	require.True(t, math.IsInf(s[0].Distance, 1))
+
+	angle := 0.1
+	soft := NewDirectionalLight(geom.NewVector(0, -1, 0), angle, 16, colors.White(), nil)
+	s = soft.SamplesFrom(geom.ZeroPoint())
+
+	require.Len(t, s, 16)
+	for _, sample := range s {
+		require.InDelta(t, 1, sample.Direction.Mag(), 1e-9)
+		require.True(t, sample.Direction.Y >= math.Cos(angle/2)-1e-9)
+		require.True(t, math.IsInf(sample.Distance, 1))
+	}
+}
@@ -0,0 +1,135 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"math"
+)
+
+// minAlpha keeps perfectly smooth surfaces from dividing by zero, they are left to mirror reflection
+const minAlpha = 0.002
+
+// dielectricF0 is the reflectance of non metals looking straight at them, around 4% for most
+const dielectricF0 = 0.04
+
+// GGX is the Trowbridge-Reitz distribution of microfacet normals, for the cosine between the normal and half vector
+func GGX(nDotH, alpha float64) float64 {
+	if nDotH <= 0 {
+		return 0
+	}
+	a2 := alpha * alpha
+	d := nDotH*nDotH*(a2-1) + 1
+	return a2 / (math.Pi * d * d)
+}
+
+// SmithG is the fraction of microfacets seen from both the eye and the light, from separable Smith GGX masking
+func SmithG(nDotV, nDotL, alpha float64) float64 {
+	return smithG1(nDotV, alpha) * smithG1(nDotL, alpha)
+}
+
+func smithG1(nDotX, alpha float64) float64 {
+	if nDotX <= 0 {
+		return 0
+	}
+	a2 := alpha * alpha
+	return 2 * nDotX / (nDotX + math.Sqrt(a2+(1-a2)*nDotX*nDotX))
+}
+
+// GGXAlpha turns perceptual roughness from 0 to 1 into the width of the GGX distribution
+func GGXAlpha(roughness float64) float64 {
+	r := math.Max(0, math.Min(1, roughness))
+	return math.Max(minAlpha, r*r)
+}
+
+// SampleGGXNormal maps u, v to a microfacet normal around n, picked in proportion to GGX(n.h) * n.h
+func SampleGGXNormal(n geom.Tuple, alpha, u, v float64) geom.Tuple {
+	tan2 := alpha * alpha * u / math.Max(1e-12, 1-u)
+	cosTheta := 1 / math.Sqrt(1+tan2)
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := 2 * math.Pi * v
+	t, b := geom.OrthonormalBasis(n)
+	return geom.ToBasis(geom.NewVector(sinTheta*math.Cos(phi), sinTheta*math.Sin(phi), cosTheta), t, b, n)
+}
+
+// SchlickFresnel is the reflectance at an angle with cosine cos, for a surface reflecting f0 head on
+func SchlickFresnel(f0 colors.Color, cos float64) colors.Color {
+	k := math.Pow(1-math.Max(0, math.Min(1, cos)), 5)
+	return f0.Add(colors.White().Sub(f0).MulBy(k))
+}
+
+// microfacetBSDF is a cook-torrance specular lobe over a lambertian base, the diffuse part fading out for metals
+type microfacetBSDF struct {
+	base     colors.Color
+	metallic float64
+	alpha    float64
+	f0       colors.Color
+	normal   geom.Tuple
+	eye      geom.Tuple
+	// chance of sampling the specular lobe instead of the diffuse one
+	pSpecular float64
+}
+
+func newMicrofacetBSDF(m materials.Material, base colors.Color, normal, eyev geom.Tuple) microfacetBSDF {
+	metallic := math.Max(0, math.Min(1, m.Metallic))
+	f0 := colors.White().MulBy(dielectricF0).MulBy(1 - metallic).Add(base.MulBy(metallic))
+
+	// sample in proportion to how much each lobe reflects when looking at the surface
+	fresnel := SchlickFresnel(f0, normal.Dot(eyev))
+	specular := (fresnel.R + fresnel.G + fresnel.B) / 3
+	diffuse := (1 - metallic) * (1 - specular) * math.Max(0, (base.R+base.G+base.B)/3)
+	pSpecular := 1.0
+	if specular+diffuse > 0 {
+		pSpecular = specular / (specular + diffuse)
+	}
+
+	return microfacetBSDF{
+		base:      base,
+		metallic:  metallic,
+		alpha:     GGXAlpha(m.Roughness),
+		f0:        f0,
+		normal:    normal,
+		eye:       eyev,
+		pSpecular: pSpecular,
+	}
+}
+
+func (b microfacetBSDF) Eval(wi geom.Tuple) colors.Color {
+	nDotL := b.normal.Dot(wi)
+	nDotV := b.normal.Dot(b.eye)
+	if nDotL <= 0 || nDotV <= 0 {
+		return colors.Black()
+	}
+	h := wi.Add(b.eye).Normalize()
+	fresnel := SchlickFresnel(b.f0, b.eye.Dot(h))
+
+	specular := fresnel.MulBy(GGX(b.normal.Dot(h), b.alpha) * SmithG(nDotV, nDotL, b.alpha) / (4 * nDotL * nDotV))
+	diffuse := colors.White().Sub(fresnel).Mul(b.base).MulBy((1 - b.metallic) / math.Pi)
+	return specular.Add(diffuse)
+}
+
+func (b microfacetBSDF) Pdf(wi geom.Tuple) float64 {
+	nDotL := b.normal.Dot(wi)
+	if nDotL <= 0 {
+		return 0
+	}
+	h := wi.Add(b.eye).Normalize()
+	vDotH := b.eye.Dot(h)
+	specular := 0.0
+	if vDotH > 0 {
+		// half vectors are picked by D * cos, reflecting about them changes the density by 1 / (4 v.h)
+		specular = GGX(b.normal.Dot(h), b.alpha) * b.normal.Dot(h) / (4 * vDotH)
+	}
+	return b.pSpecular*specular + (1-b.pSpecular)*nDotL/math.Pi
+}
+
+func (b microfacetBSDF) Sample(u, v, w float64) (geom.Tuple, bool) {
+	if u >= b.pSpecular {
+		t, bt := geom.OrthonormalBasis(b.normal)
+		return geom.ToBasis(geom.SampleCosineHemisphere(v, w), t, bt, b.normal), true
+	}
+
+	h := SampleGGXNormal(b.normal, b.alpha, v, w)
+	wi := b.eye.Neg().Reflect(h)
+	return wi, wi.Dot(b.normal) > 0
+}
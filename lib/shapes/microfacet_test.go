@@ -0,0 +1,114 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func Test_GGX_Normalized(t *testing.T) {
+	// projected microfacet area adds up to the macro surface
+	rng := rand.New(rand.NewSource(1))
+	for _, alpha := range []float64{0.2, 0.5, 1} {
+		sum := 0.0
+		n := 200000
+		for i := 0; i < n; i++ {
+			h := geom.SampleSphere(rng.Float64(), rng.Float64())
+			sum += GGX(h.Z, alpha) * math.Max(0, h.Z) * 4 * math.Pi
+		}
+		require.InDelta(t, 1, sum/float64(n), 0.03)
+	}
+}
+
+func Test_SmithG(t *testing.T) {
+	require.InDelta(t, 1, SmithG(1, 1, 0.5), 1e-9)
+	require.Equal(t, 0.0, SmithG(0.5, 0, 0.5))
+	require.True(t, SmithG(0.1, 0.1, 0.8) < SmithG(0.1, 0.1, 0.2))
+	require.True(t, SmithG(0.1, 0.9, 0.5) > 0 && SmithG(0.1, 0.9, 0.5) < 1)
+}
+
+func Test_SchlickFresnel(t *testing.T) {
+	f0 := colors.NewColor(0.04, 0.5, 1)
+
+	require.Equal(t, f0, SchlickFresnel(f0, 1))
+	require.Equal(t, colors.White(), SchlickFresnel(f0, 0))
+	require.True(t, SchlickFresnel(f0, 0.2).R > f0.R)
+}
+
+func Test_MicrofacetBSDF_ConservesEnergy(t *testing.T) {
+	type args struct {
+		metallic  float64
+		roughness float64
+	}
+
+	tests := []args{
+		{0, 1},
+		{0, 0.5},
+		{0, 0.1},
+		{1, 0.8},
+		{1, 0.3},
+	}
+
+	normal := geom.NewVector(0, 1, 0)
+	eye := geom.NewVector(0.5, 1, 0).Normalize()
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			m := materials.NewMetallicRoughnessMaterial(colors.White(), tt.metallic, tt.roughness)
+			b := newMicrofacetBSDF(m, m.Color, normal, eye)
+			rng := rand.New(rand.NewSource(1))
+
+			// importance sampled and uniform estimates of the reflected fraction agree, and stay below 1.
+			// very rough metals lose light that would bounce between microfacets more than once
+			sampled := 0.0
+			uniform := 0.0
+			n := 100000
+			for i := 0; i < n; i++ {
+				if wi, ok := b.Sample(rng.Float64(), rng.Float64(), rng.Float64()); ok {
+					if pdf := b.Pdf(wi); pdf > 0 {
+						sampled += b.Eval(wi).G * wi.Dot(normal) / pdf
+					}
+				}
+				wi := geom.SampleSphere(rng.Float64(), rng.Float64())
+				uniform += b.Eval(wi).G * math.Max(0, wi.Dot(normal)) * 4 * math.Pi
+			}
+			sampled /= float64(n)
+			uniform /= float64(n)
+
+			require.True(t, sampled <= 1.01, "%f", sampled)
+			require.True(t, sampled > 0.5, "%f", sampled)
+			require.InDelta(t, uniform, sampled, 0.05)
+		})
+	}
+}
+
+func Test_Lighting_MetallicRoughness(t *testing.T) {
+	light := NewPointLight(geom.NewPoint(0, 0, -10), colors.White())
+	eyeV := geom.NewVector(0, 0, -1)
+	normalV := geom.NewVector(0, 0, -1)
+	p := geom.ZeroPoint()
+
+	// metals tint their highlight, dielectrics reflect it white
+	metal := materials.NewMetallicRoughnessMaterial(colors.Red(), 1, 0.3)
+	plastic := materials.NewMetallicRoughnessMaterial(colors.Red(), 0, 0.3)
+	cMetal := Lighting(metal, NewSphere(), light, p, eyeV, normalV, 1.0)
+	cPlastic := Lighting(plastic, NewSphere(), light, p, eyeV, normalV, 1.0)
+
+	require.True(t, cMetal.R > 1)
+	require.Equal(t, 0.0, cMetal.G)
+	require.True(t, cPlastic.G > 0)
+	require.True(t, cPlastic.R > cPlastic.G)
+
+	// a rough white dielectric is about as bright as phong diffuse, shadows and ambient work the same
+	rough := materials.NewMetallicRoughnessMaterial(colors.White(), 0, 1)
+	require.InDelta(t, 1.0, Lighting(rough, NewSphere(), light, p, eyeV, normalV, 1.0).R, 0.15)
+	require.Equal(t, colors.White().MulBy(rough.Ambient), Lighting(rough, NewSphere(), light, p, eyeV, normalV, 0.0))
+
+	// light from behind does nothing
+	behind := NewPointLight(geom.NewPoint(0, 0, 10), colors.White())
+	require.Equal(t, colors.White().MulBy(rough.Ambient), Lighting(rough, NewSphere(), behind, p, eyeV, normalV, 1.0))
+}
@@ -32,6 +32,10 @@ func (p *Plane) Intersect(r geom.Ray) *Intersections {
 	return Intersect(r, p.t, p.LocalIntersect)
 }
 
+func (p *Plane) AnyHit(r geom.Ray, maxT float64) bool {
+	return AnyHit(p, r, maxT, p.LocalIntersect)
+}
+
 func (p *Plane) LocalIntersect(r geom.Ray) *Intersections {
 	if math.Abs(r.Direction.Y) < geom.FloatComparisonEpsilon {
 		return NewIntersections()
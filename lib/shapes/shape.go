@@ -9,6 +9,8 @@ import (
 type Shape interface {
 	Intersect(geom.Ray) *Intersections
 	LocalIntersect(r geom.Ray) *Intersections
+	// AnyHit reports if the ray hits anything casting shadows between 0 and maxT, without collecting intersections
+	AnyHit(r geom.Ray, maxT float64) bool
 	NormalAt(at geom.Tuple, i Intersection) geom.Tuple
 	WorldToObject(p geom.Tuple) geom.Tuple
 	NormalToWorld(normal geom.Tuple) geom.Tuple
@@ -144,9 +146,31 @@ func Intersect(r geom.Ray, t *geom.X4Matrix, lif func(geom.Ray) *Intersections)
 	return lif(lr)
 }
 
+// AnyHit inverts ray from object's transformation matrix then checks the shape-specific intersections for one between 0 and maxT
+func AnyHit(s Shape, r geom.Ray, maxT float64, lif func(geom.Ray) *Intersections) bool {
+	if s.GetShadowless() {
+		return false
+	}
+	for _, i := range Intersect(r, s.GetTransform(), lif).I {
+		if i.T > 0 && i.T < maxT {
+			return true
+		}
+	}
+	return false
+}
+
 // ParentSpaceBoundsOf is for transformed shape
 func ParentSpaceBoundsOf(s Shape) *BoundingBox {
 	bb := s.BoundsOf()
 	bb.Transform(s.GetTransform())
 	return bb
 }
+
+// WorldBoundsOf transforms the bounds of the shape through every parent group
+func WorldBoundsOf(s Shape) *BoundingBox {
+	bb := ParentSpaceBoundsOf(s)
+	for g := s.GetParent(); g != nil; g = g.GetParent() {
+		bb.Transform(g.GetTransform())
+	}
+	return bb
+}
@@ -145,6 +145,11 @@ func (t *testShape) Intersect(r geom.Ray) *Intersections {
 	return &Intersections{}
 }
 
+func (t *testShape) AnyHit(r geom.Ray, maxT float64) bool {
+	t.savedRay = r.Transform(t.t.Invert())
+	return false
+}
+
 func (t *testShape) LocalIntersect(r geom.Ray) *Intersections {
 	return NewIntersections()
 }
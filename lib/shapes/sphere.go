@@ -32,6 +32,10 @@ func (s *Sphere) Intersect(r geom.Ray) *Intersections {
 	return Intersect(r, s.t, s.LocalIntersect)
 }
 
+func (s *Sphere) AnyHit(r geom.Ray, maxT float64) bool {
+	return AnyHit(s, r, maxT, s.LocalIntersect)
+}
+
 func (s *Sphere) LocalIntersect(r geom.Ray) *Intersections {
 	sr := r.Origin.Sub(geom.ZeroPoint())
 	a := r.Direction.Dot(r.Direction)
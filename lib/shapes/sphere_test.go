@@ -4,7 +4,9 @@ import (
 	"github.com/robkau/go-raytrace/lib/geom"
 	"github.com/robkau/go-raytrace/lib/materials"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"math"
+	"strconv"
 	"testing"
 )
 
@@ -201,3 +203,34 @@ func Test_Normal_ScaledAndRotated(t *testing.T) {
 
 	assert.Equal(t, geom.NewVector(0, 0.97014, -0.24254), n)
 }
+
+func Test_Sphere_AnyHit(t *testing.T) {
+	type args struct {
+		origin     geom.Tuple
+		maxT       float64
+		shadowless bool
+		expect     bool
+	}
+
+	tests := []args{
+		{geom.NewPoint(0, 0, -5), 10, false, true},
+		// light is in front of the sphere
+		{geom.NewPoint(0, 0, -5), 3, false, false},
+		// sphere is behind the ray
+		{geom.NewPoint(0, 0, 5), 10, false, false},
+		// inside the sphere
+		{geom.NewPoint(0, 0, 0), 10, false, true},
+		{geom.NewPoint(0, 0, -5), 10, true, false},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			s := NewSphere()
+			s.SetTransform(geom.Scale(2, 2, 2))
+			s.SetShadowless(tt.shadowless)
+			r := geom.RayWith(tt.origin, geom.NewVector(0, 0, 1))
+
+			require.Equal(t, tt.expect, s.AnyHit(r, tt.maxT))
+		})
+	}
+}
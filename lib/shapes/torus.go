@@ -0,0 +1,95 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/patterns"
+	"math"
+)
+
+// Torus is a ring centered at the origin, lying in the xz plane around the y axis
+type Torus struct {
+	baseShape
+
+	major float64
+	minor float64
+}
+
+// NewTorus creates a torus with major radius from the origin to the center of the tube, and minor radius of the tube itself
+func NewTorus(major, minor float64) *Torus {
+	return &Torus{
+		baseShape: newBaseShape(),
+		major:     major,
+		minor:     minor,
+	}
+}
+
+func (t *Torus) Radii() (major, minor float64) {
+	return t.major, t.minor
+}
+
+// BoundsOf is for untransformed shape
+func (t *Torus) BoundsOf() *BoundingBox {
+	outer := t.major + t.minor
+	return NewBoundingBox(geom.NewPoint(-outer, -t.minor, -outer), geom.NewPoint(outer, t.minor, outer))
+}
+
+func (t *Torus) NormalAt(p geom.Tuple, _ Intersection) geom.Tuple {
+	return NormalAt(t, p, t.LocalNormalAt, Intersection{})
+}
+
+func (t *Torus) LocalNormalAt(p geom.Tuple, _ Intersection) geom.Tuple {
+	// normal points away from the closest point on the circle through the center of the tube
+	d := math.Sqrt(p.X*p.X + p.Z*p.Z)
+	if d == 0 {
+		return geom.NewVector(0, p.Y, 0)
+	}
+	return geom.NewVector(p.X-p.X*t.major/d, p.Y, p.Z-p.Z*t.major/d)
+}
+
+func (t *Torus) Intersect(r geom.Ray) *Intersections {
+	return Intersect(r, t.t, t.LocalIntersect)
+}
+
+func (t *Torus) AnyHit(r geom.Ray, maxT float64) bool {
+	return AnyHit(t, r, maxT, t.LocalIntersect)
+}
+
+func (t *Torus) LocalIntersect(r geom.Ray) *Intersections {
+	tMin, tMax := intersectsCube(r, t.BoundsOf())
+	if tMin > tMax {
+		return NewIntersections()
+	}
+
+	// solving from the bounding box entry keeps the quartic coefficients small for far away rays
+	shift := 0.0
+	if !math.IsInf(tMin, 0) && !math.IsNaN(tMin) {
+		shift = tMin
+	}
+	o := r.Position(shift)
+	d := r.Direction
+
+	// (x^2 + y^2 + z^2 - R^2 - r^2)^2 = 4R^2(r^2 - y^2), with the ray substituted for x, y and z
+	dd := d.X*d.X + d.Y*d.Y + d.Z*d.Z
+	od := o.X*d.X + o.Y*d.Y + o.Z*d.Z
+	e := o.X*o.X + o.Y*o.Y + o.Z*o.Z - t.major*t.major - t.minor*t.minor
+	fourRSquared := 4 * t.major * t.major
+
+	roots := geom.SolveQuartic(
+		dd*dd,
+		4*dd*od,
+		2*dd*e+4*od*od+fourRSquared*d.Y*d.Y,
+		4*od*e+2*fourRSquared*o.Y*d.Y,
+		e*e-fourRSquared*(t.minor*t.minor-o.Y*o.Y),
+	)
+
+	xs := make([]Intersection, 0, len(roots))
+	for _, root := range roots {
+		xs = append(xs, NewIntersection(root+shift, t))
+	}
+	return NewIntersections(xs...)
+}
+
+// UvMap maps points on the surface of this torus to texture coordinates
+func (t *Torus) UvMap() patterns.UvMappingF {
+	return patterns.NewToroidalMap(t.major)
+}
@@ -0,0 +1,148 @@
+package shapes
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/patterns"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func Test_Torus_Radii(t *testing.T) {
+	to := NewTorus(2, 0.5)
+
+	major, minor := to.Radii()
+
+	require.Equal(t, 2.0, major)
+	require.Equal(t, 0.5, minor)
+}
+
+func Test_Torus_RayIntersects(t *testing.T) {
+	type args struct {
+		origin    geom.Tuple
+		direction geom.Tuple
+		expect    []float64
+	}
+
+	tests := []args{
+		// through the whole ring along x
+		{geom.NewPoint(-5, 0, 0), geom.NewVector(1, 0, 0), []float64{2.5, 3.5, 6.5, 7.5}},
+		// through one side of the tube from above
+		{geom.NewPoint(2, 5, 0), geom.NewVector(0, -1, 0), []float64{4.5, 5.5}},
+		// through the hole
+		{geom.NewPoint(0, 5, 0), geom.NewVector(0, -1, 0), []float64{}},
+		// misses entirely
+		{geom.NewPoint(-5, 3, 0), geom.NewVector(1, 0, 0), []float64{}},
+		// unnormalized direction
+		{geom.NewPoint(-5, 0, 0), geom.NewVector(2, 0, 0), []float64{1.25, 1.75, 3.25, 3.75}},
+		// starts inside the tube
+		{geom.NewPoint(2, 0, 0), geom.NewVector(0, 0, 1), []float64{-1.5, 1.5}},
+		// far away ray
+		{geom.NewPoint(-10000, 0, 0), geom.NewVector(1, 0, 0), []float64{9997.5, 9998.5, 10001.5, 10002.5}},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			to := NewTorus(2, 0.5)
+			r := geom.RayWith(tt.origin, tt.direction)
+
+			xs := to.LocalIntersect(r)
+
+			require.Len(t, xs.I, len(tt.expect))
+			for i := range tt.expect {
+				require.InDelta(t, tt.expect[i], xs.I[i].T, 1e-6)
+				require.Equal(t, to, xs.I[i].O)
+			}
+		})
+	}
+}
+
+func Test_Torus_TangentRayIntersectsInPairs(t *testing.T) {
+	type args struct {
+		origin    geom.Tuple
+		direction geom.Tuple
+		touch     float64
+	}
+
+	tests := []args{
+		// grazes the outer equator
+		{geom.NewPoint(2.5, 5, 0), geom.NewVector(0, -1, 0), 5},
+		// grazes the top of the tube on both sides
+		{geom.NewPoint(-5, 0.5, 0), geom.NewVector(1, 0, 0), 3},
+		// grazes the inner equator
+		{geom.NewPoint(1.5, 5, 0), geom.NewVector(0, -1, 0), 5},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			to := NewTorus(2, 0.5)
+			r := geom.RayWith(tt.origin, tt.direction)
+
+			xs := to.LocalIntersect(r)
+
+			// every entry into the torus has a matching exit
+			require.Equal(t, 0, len(xs.I)%2, "intersections: %v", xs.I)
+			if len(xs.I) > 0 {
+				require.InDelta(t, tt.touch, xs.I[0].T, 1e-3)
+			}
+		})
+	}
+}
+
+func Test_Torus_Normal(t *testing.T) {
+	type args struct {
+		p      geom.Tuple
+		expect geom.Tuple
+	}
+
+	tests := []args{
+		{geom.NewPoint(2.5, 0, 0), geom.NewVector(1, 0, 0)},
+		{geom.NewPoint(1.5, 0, 0), geom.NewVector(-1, 0, 0)},
+		{geom.NewPoint(0, 0.5, 2), geom.NewVector(0, 1, 0)},
+		{geom.NewPoint(0, -0.5, -2), geom.NewVector(0, -1, 0)},
+		{geom.NewPoint(2+0.5*math.Sqrt2/2, 0.5*math.Sqrt2/2, 0), geom.NewVector(math.Sqrt2/2, math.Sqrt2/2, 0)},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			to := NewTorus(2, 0.5)
+			n := to.NormalAt(tt.p, Intersection{})
+			require.Equal(t, tt.expect.RoundTo(5), n.RoundTo(5))
+		})
+	}
+}
+
+func Test_Torus_Bounds(t *testing.T) {
+	to := NewTorus(2, 0.5)
+
+	box := to.BoundsOf()
+
+	require.Equal(t, geom.NewPoint(-2.5, -0.5, -2.5), box.Min)
+	require.Equal(t, geom.NewPoint(2.5, 0.5, 2.5), box.Max)
+}
+
+func Test_Torus_TransformedIntersect(t *testing.T) {
+	to := NewTorus(1, 0.25)
+	to.SetTransform(geom.Translate(0, 0, 5).MulX4Matrix(geom.RotateX(math.Pi / 2)))
+	r := geom.RayWith(geom.ZeroPoint(), geom.NewVector(0, 0, 1))
+
+	// ray goes through the hole of the torus standing upright
+	xs := to.Intersect(r)
+	require.Len(t, xs.I, 0)
+
+	r = geom.RayWith(geom.NewPoint(1, 0, 0), geom.NewVector(0, 0, 1))
+	xs = to.Intersect(r)
+	require.Len(t, xs.I, 2)
+	require.InDelta(t, 4.75, xs.I[0].T, 1e-6)
+	require.InDelta(t, 5.25, xs.I[1].T, 1e-6)
+}
+
+func Test_Torus_TextureMap(t *testing.T) {
+	to := NewTorus(2, 0.5)
+	p := patterns.NewTextureMapPattern(patterns.NewCheckerPatternUV(4, 2, colors.Black(), colors.White()), to.UvMap())
+
+	require.Equal(t, colors.White(), p.ColorAt(geom.NewPoint(0, 0, -2.5)))
+	require.Equal(t, colors.Black(), p.ColorAt(geom.NewPoint(0, -0.5, -2)))
+}
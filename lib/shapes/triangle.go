@@ -40,6 +40,15 @@ func (t *Triangle) Intersect(ray geom.Ray) *Intersections {
 	return Intersect(ray, t.t, t.LocalIntersect)
 }
 
+// AnyHit skips building intersections, meshes are made of many triangles
+func (t *Triangle) AnyHit(r geom.Ray, maxT float64) bool {
+	if t.GetShadowless() {
+		return false
+	}
+	tHit, _, _, ok := t.localIntersectHits(r.Transform(t.t.Invert()))
+	return ok && tHit > 0 && tHit < maxT
+}
+
 func (t *Triangle) LocalIntersect(r geom.Ray) *Intersections {
 	tHit, _, _, ok := t.localIntersectHits(r)
 	if !ok {
@@ -96,3 +96,17 @@ func Test_TriangleIntersectDoesNotStoreUV(t *testing.T) {
 	require.Len(t, xs.I, 1)
 	require.False(t, xs.I[0].UvSet)
 }
+
+func Test_Triangle_AnyHit(t *testing.T) {
+	tr := NewTriangle(geom.NewPoint(0, 1, 0), geom.NewPoint(-1, 0, 0), geom.NewPoint(1, 0, 0))
+	tr.SetTransform(geom.Translate(0, 0, 1))
+	r := geom.RayWith(geom.NewPoint(0, 0.5, -2), geom.NewVector(0, 0, 1))
+
+	require.True(t, tr.AnyHit(r, 4))
+	require.False(t, tr.AnyHit(r, 3))
+	require.False(t, tr.AnyHit(geom.RayWith(geom.NewPoint(0, 0.5, -2), geom.NewVector(0, 0, -1)), 4))
+	require.False(t, tr.AnyHit(geom.RayWith(geom.NewPoint(1, 1, -2), geom.NewVector(0, 0, 1)), 4))
+
+	tr.SetShadowless(true)
+	require.False(t, tr.AnyHit(r, 4))
+}
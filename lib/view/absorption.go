@@ -0,0 +1,29 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+)
+
+// hasMedia reports if any primitive in the shape tree absorbs or scatters light passing through it
+func hasMedia(s shapes.Shape) bool {
+	if g, ok := s.(shapes.Group); ok {
+		for _, c := range g.GetChildren() {
+			if hasMedia(c) {
+				return true
+			}
+		}
+		return false
+	}
+	m := s.GetMaterial()
+	return materials.IsAbsorbing(m) || materials.IsParticipating(m.Medium)
+}
+
+// transmittance is the light left after the ray crossed its medium to reach the hit
+func transmittance(c shapes.IntersectionComputed) colors.Color {
+	if c.Medium == nil {
+		return colors.White()
+	}
+	return materials.Transmittance(c.Medium.GetMaterial(), c.Distance)
+}
@@ -0,0 +1,106 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// absorbingSphereWorld is a clear sphere that does not bend light, tinting what passes through it, under a white sky
+func absorbingSphereWorld() *World {
+	w := NewWorld()
+	w.SetEnvironment(NewConstantEnvironment(colors.White()))
+	s := shapes.NewSphere()
+	m := s.GetMaterial()
+	m.Color = colors.Black()
+	m.Ambient = 0
+	m.Diffuse = 0
+	m.Specular = 0
+	m.Transparency = 1
+	m.RefractiveIndex = 1
+	m.AbsorptionColor = colors.NewColor(0.5, 0.8, 1)
+	m.AbsorptionDistance = 1
+	s.SetMaterial(m)
+	w.AddObject(s)
+	return w
+}
+
+func Test_Absorption_ScalesWithDistanceInside(t *testing.T) {
+	type args struct {
+		offset float64
+	}
+
+	tests := []args{
+		{offset: 0},
+		{offset: 0.6},
+		{offset: 0.9},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			// a chord through the unit sphere
+			inside := 2 * math.Sqrt(1-tt.offset*tt.offset)
+			expected := colors.NewColor(math.Pow(0.5, inside), math.Pow(0.8, inside), 1)
+			r := geom.RayWith(geom.NewPoint(tt.offset, 0, -5), geom.NewVector(0, 0, 1))
+
+			w := absorbingSphereWorld()
+			requireColorInDelta(t, expected, w.ColorAt(r, 3))
+			requireColorInDelta(t, expected, NewPathTracer().ColorAt(w, r, 3, rand.New(rand.NewSource(1))))
+
+			w.Compile()
+			requireColorInDelta(t, expected, w.ColorAt(r, 3))
+		})
+	}
+}
+
+func Test_Absorption_OpaqueObjectInside(t *testing.T) {
+	w := absorbingSphereWorld()
+	// a glowing white card in the middle of the sphere
+	card := shapes.NewCube()
+	card.SetTransform(geom.Scale(0.5, 0.5, 0.01))
+	m := card.GetMaterial()
+	m.Ambient = 0
+	m.Emission = colors.White()
+	m.Diffuse = 0
+	m.Specular = 0
+	card.SetMaterial(m)
+	w.AddObject(card)
+	w.Compile()
+
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	// the front of the card is 0.99 inside the sphere
+	requireColorInDelta(t, colors.NewColor(math.Pow(0.5, 0.99), math.Pow(0.8, 0.99), 1), w.ColorAt(r, 3))
+}
+
+func Test_HasMedia(t *testing.T) {
+	w := absorbingSphereWorld()
+	require.True(t, w.media)
+	require.False(t, defaultWorld().media)
+
+	g := shapes.NewGroup()
+	s := shapes.NewSphere()
+	m := materials.NewGlassMaterial()
+	require.False(t, hasMedia(g))
+
+	m.AbsorptionDistance = 3
+	s.SetMaterial(m)
+	g.AddChild(s)
+	require.True(t, hasMedia(g))
+
+	smoke := shapes.NewCube()
+	smoke.SetMaterial(materials.NewMediumMaterial(materials.NewMedium(colors.White(), 0, 0.1)))
+	require.True(t, hasMedia(smoke))
+}
+
+func requireColorInDelta(t *testing.T, expected, actual colors.Color) {
+	require.InDelta(t, expected.R, actual.R, 1e-3)
+	require.InDelta(t, expected.G, actual.G, 1e-3)
+	require.InDelta(t, expected.B, actual.B, 1e-3)
+}
@@ -0,0 +1,112 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"math"
+	"math/rand"
+)
+
+// pixelCenters holds the first pass of an adaptive render, one center sample per pixel
+type pixelCenters struct {
+	width  int
+	height int
+	c      []colors.Color
+}
+
+func newPixelCenters(width, height int) *pixelCenters {
+	return &pixelCenters{
+		width:  width,
+		height: height,
+		c:      make([]colors.Color, width*height),
+	}
+}
+
+func (p *pixelCenters) at(x, y int) colors.Color {
+	return p.c[y*p.width+x]
+}
+
+func (p *pixelCenters) set(x, y int, c colors.Color) {
+	p.c[y*p.width+x] = c
+}
+
+// needsRefinement compares a pixel against its 8 neighbours
+func (p *pixelCenters) needsRefinement(x, y int, threshold float64) bool {
+	c := p.at(x, y)
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := x+dx, y+dy
+			if nx < 0 || ny < 0 || nx >= p.width || ny >= p.height || (dx == 0 && dy == 0) {
+				continue
+			}
+			if contrast(c, p.at(nx, ny)) > threshold {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contrast is the largest difference of any color channel
+func contrast(a, b colors.Color) float64 {
+	return math.Max(math.Abs(a.R-b.R), math.Max(math.Abs(a.G-b.G), math.Abs(a.B-b.B)))
+}
+
+// adaptiveColorForPixel refines a pixel of the first pass if it stands out from its neighbours
+func (c Camera) adaptiveColorForPixel(w *World, centers *pixelCenters, px, py int, rayBounces int, rng *rand.Rand) (colors.Color, int) {
+	center := centers.at(px, py)
+	if c.Sampling.MaxDepth < 1 || !centers.needsRefinement(px, py, c.Sampling.Threshold) {
+		return center, 1
+	}
+	col, samples := c.subdivide(w, px, py, 0, 0, 1, center, 1, rayBounces, rng)
+	return col, samples + 1
+}
+
+// subdivide splits a square of the pixel into quadrants and samples each of their centers.
+// quadrants that differ from the parent center are split again until MaxDepth
+func (c Camera) subdivide(w *World, px, py int, x0, y0, size float64, center colors.Color, depth int, rayBounces int, rng *rand.Rand) (colors.Color, int) {
+	half := size / 2
+	sum := colors.Black()
+	samples := 0
+	for _, q := range [4][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		qx, qy := x0+q[0]*half, y0+q[1]*half
+		col := c.colorAt(w, px, py, qx+half/2, qy+half/2, rayBounces, rng)
+		samples++
+		if depth < c.Sampling.MaxDepth && contrast(col, center) > c.Sampling.Threshold {
+			var n int
+			col, n = c.subdivide(w, px, py, qx, qy, half, col, depth+1, rayBounces, rng)
+			samples += n
+		}
+		sum = sum.Add(col)
+	}
+	return sum.MulBy(0.25), samples
+}
+
+// MaxSamples is the most rays a single pixel can receive with these settings
+func (s Sampling) MaxSamples() int {
+	if s.Pattern == SampleAdaptive {
+		// the center sample, then 4 more for every quadrant at each depth
+		total, level := 1, 1
+		for d := 0; d < s.MaxDepth; d++ {
+			level *= 4
+			total += level
+		}
+		return total
+	}
+	if s.SamplesPerPixel < 1 {
+		return 1
+	}
+	return s.SamplesPerPixel
+}
+
+// HeatmapColor shades a sample count from blue (fewest) through green to red (most)
+func HeatmapColor(samples, maxSamples int) colors.Color {
+	t := 0.0
+	if maxSamples > 1 {
+		t = float64(samples-1) / float64(maxSamples-1)
+	}
+	t = math.Max(0, math.Min(1, t))
+	if t < 0.5 {
+		return colors.NewColor(0, 2*t, 1-2*t)
+	}
+	return colors.NewColor(2*t-1, 2-2*t, 0)
+}
@@ -0,0 +1,122 @@
+package view
+
+import (
+	"context"
+	"github.com/robkau/coordinate_supplier"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func Test_PixelCenters_NeedsRefinement(t *testing.T) {
+	p := newPixelCenters(3, 3)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			p.set(x, y, colors.NewColor(0.5, 0.5, 0.5))
+		}
+	}
+	p.set(2, 2, colors.NewColor(0.5, 0.5, 0.8))
+
+	require.False(t, p.needsRefinement(0, 0, 0.1))
+	require.True(t, p.needsRefinement(1, 1, 0.1))
+	require.True(t, p.needsRefinement(2, 2, 0.1))
+	require.False(t, p.needsRefinement(1, 1, 0.5))
+}
+
+func Test_Sampling_MaxSamples(t *testing.T) {
+	type args struct {
+		s      Sampling
+		expect int
+	}
+
+	tests := []args{
+		{Sampling{}, 1},
+		{Sampling{SamplesPerPixel: 5}, 5},
+		{Sampling{SamplesPerPixel: 5, Pattern: SampleJittered}, 5},
+		{Sampling{Pattern: SampleAdaptive}, 1},
+		{Sampling{Pattern: SampleAdaptive, MaxDepth: 1}, 5},
+		{Sampling{Pattern: SampleAdaptive, MaxDepth: 3}, 85},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			require.Equal(t, tt.expect, tt.s.MaxSamples())
+		})
+	}
+}
+
+func Test_HeatmapColor(t *testing.T) {
+	require.Equal(t, colors.NewColor(0, 0, 1), HeatmapColor(1, 5))
+	require.Equal(t, colors.NewColor(0, 1, 0), HeatmapColor(3, 5))
+	require.Equal(t, colors.NewColor(1, 0, 0), HeatmapColor(5, 5))
+	require.Equal(t, colors.NewColor(0, 0, 1), HeatmapColor(1, 1))
+}
+
+func Test_Render_Adaptive(t *testing.T) {
+	// the left edge of a white cube runs down the middle column of pixels
+	w := NewWorld()
+	w.AddPointLight(shapes.NewPointLight(geom.NewPoint(0, 0, -10), colors.White()))
+	cube := shapes.NewCube()
+	cube.SetTransform(geom.Translate(5, 0, 0).MulX4Matrix(geom.Scale(5, 5, 1)))
+	m := materials.NewMaterial()
+	m.Color = colors.White()
+	m.Ambient = 1
+	m.Diffuse = 0
+	m.Specular = 0
+	cube.SetMaterial(m)
+	w.AddObject(cube)
+	c := NewCamera(5, 3, math.Pi/2).WithSampling(Sampling{Pattern: SampleAdaptive, Threshold: 0.1, MaxDepth: 2})
+	c.Transform = geom.ViewTransform(geom.NewPoint(0, 0, -5), geom.ZeroPoint(), geom.UpVector())
+
+	pc, err := Render(context.Background(), w, c, 0, 2, coordinate_supplier.Asc)
+	require.NoError(t, err)
+
+	pixels := map[[2]int]PixelInfo{}
+	for p := range pc {
+		pixels[[2]int{p.X, p.Y}] = p
+	}
+	require.Len(t, pixels, 15)
+	for y := 0; y < 3; y++ {
+		// flat pixels away from the edge keep their single sample
+		require.Equal(t, 1, pixels[[2]int{0, y}].Samples)
+		require.Equal(t, colors.Black(), pixels[[2]int{0, y}].C)
+		require.Equal(t, 1, pixels[[2]int{4, y}].Samples)
+		require.Equal(t, colors.White(), pixels[[2]int{4, y}].C)
+
+		// the edge pixel is split, only its quadrants on the edge are split again
+		edge := pixels[[2]int{2, y}]
+		require.Equal(t, 1+4+8, edge.Samples)
+		require.InDelta(t, 0.5, edge.C.R, 1e-9)
+	}
+}
+
+func Test_Render_Seed(t *testing.T) {
+	// jittered samples across the edge of a lit cube
+	w := NewWorld()
+	w.AddPointLight(shapes.NewPointLight(geom.NewPoint(0, 0, -10), colors.White()))
+	cube := shapes.NewCube()
+	cube.SetTransform(geom.Translate(5, 0, 0).MulX4Matrix(geom.Scale(5, 5, 1)))
+	w.AddObject(cube)
+	c := NewCamera(5, 3, math.Pi/2)
+	c.Transform = geom.ViewTransform(geom.NewPoint(0, 0, -5), geom.ZeroPoint(), geom.UpVector())
+
+	render := func(seed int64) PixelInfo {
+		pc, err := Render(context.Background(), w, c.WithSampling(Sampling{SamplesPerPixel: 4, Pattern: SampleJittered, Seed: seed}), 0, 1, coordinate_supplier.Asc)
+		require.NoError(t, err)
+		var edge PixelInfo
+		for p := range pc {
+			if p.X == 2 && p.Y == 1 {
+				edge = p
+			}
+		}
+		return edge
+	}
+
+	require.Equal(t, render(5), render(5))
+	require.NotEqual(t, render(5), render(6))
+}
@@ -1,11 +1,14 @@
 package view
 
 import (
+	"context"
+	"fmt"
+	"github.com/robkau/coordinate_supplier"
 	"github.com/robkau/go-raytrace/lib/colors"
 	"github.com/robkau/go-raytrace/lib/geom"
 	"github.com/robkau/go-raytrace/lib/view/canvas"
 	"math"
-	"sync"
+	"math/rand"
 )
 
 type Camera struct {
@@ -16,14 +19,26 @@ type Camera struct {
 	halfHeight float64
 	pixelSize  float64
 	Transform  *geom.X4Matrix
+	Sampling   Sampling
+	Lens       Lens
+	// nil is the perspective projection from Fov
+	Projection Projection
+	// nil is the WhittedIntegrator
+	Integrator Integrator
+	// distance to the point the camera looks at, the default focal distance
+	lookDistance float64
+	// stereo eyes sit eyeOffset to the side, with the image plane shifted by eyeShift
+	eyeOffset float64
+	eyeShift  float64
 }
 
 func NewCamera(hs int, vs int, fov float64) Camera {
 	c := Camera{
-		HSize:     hs,
-		VSize:     vs,
-		Fov:       fov,
-		Transform: geom.NewIdentityMatrixX4(),
+		HSize:        hs,
+		VSize:        vs,
+		Fov:          fov,
+		Transform:    geom.NewIdentityMatrixX4(),
+		lookDistance: 1,
 	}
 
 	halfView := math.Tan(c.Fov / 2)
@@ -45,43 +60,148 @@ func NewCameraAt(hs, vs int, fov float64, at geom.Tuple, lookingAt geom.Tuple) C
 	c.Transform = geom.ViewTransform(at,
 		lookingAt,
 		geom.UpVector())
+	c.lookDistance = lookingAt.Sub(at).Mag()
+	return c
+}
+
+func (c Camera) WithSampling(s Sampling) Camera {
+	c.Sampling = s
+	return c
+}
+
+func (c Camera) WithLens(l Lens) Camera {
+	c.Lens = l
+	return c
+}
+
+func (c Camera) WithIntegrator(i Integrator) Camera {
+	c.Integrator = i
+	return c
+}
+
+func (c Camera) WithProjection(p Projection) Camera {
+	c.Projection = p
 	return c
 }
 
 func (c Camera) rayForPixel(px int, py int) geom.Ray {
-	xOffset := (float64(px) + 0.5) * c.pixelSize
-	yOffset := (float64(py) + 0.5) * c.pixelSize
+	return c.rayForPixelOffset(px, py, 0.5, 0.5, nil)
+}
+
+// rayForPixelOffset fires a ray through a point of the pixel, offset from its top left corner in pixels.
+// with a lens the ray starts at a random point on the lens, a nil rng uses the lens center
+func (c Camera) rayForPixelOffset(px int, py int, ox, oy float64, rng *rand.Rand) geom.Ray {
+	r, _ := c.cameraRay(px, py, ox, oy, rng)
+	return r
+}
+
+// colorAt is black where the projection has no ray
+func (c Camera) colorAt(w *World, px int, py int, ox, oy float64, rayBounces int, rng *rand.Rand) colors.Color {
+	r, ok := c.cameraRay(px, py, ox, oy, rng)
+	if !ok {
+		return colors.Black()
+	}
+	if c.Integrator == nil {
+		return WhittedIntegrator{}.ColorAt(w, r, rayBounces, rng)
+	}
+	return c.Integrator.ColorAt(w, r, rayBounces, rng)
+}
+
+func (c Camera) cameraRay(px int, py int, ox, oy float64, rng *rand.Rand) (geom.Ray, bool) {
+	if c.Projection != nil {
+		return c.projectedRay(px, py, ox, oy, rng)
+	}
+
+	xOffset := (float64(px) + ox) * c.pixelSize
+	yOffset := (float64(py) + oy) * c.pixelSize
 
-	worldX := c.halfWidth - xOffset
+	worldX := c.halfWidth - xOffset + c.eyeShift
 	worldY := c.halfHeight - yOffset
 
-	pixel := c.Transform.Invert().MulTuple(geom.NewPoint(worldX, worldY, -1))
-	origin := c.Transform.Invert().MulTuple(geom.ZeroPoint())
-	direction := pixel.Sub(origin).Normalize()
+	if c.Lens.isPinhole() {
+		pixel := c.Transform.Invert().MulTuple(geom.NewPoint(c.eyeOffset+worldX, worldY, -1))
+		origin := c.Transform.Invert().MulTuple(geom.NewPoint(c.eyeOffset, 0, 0))
+		direction := pixel.Sub(origin).Normalize()
+
+		return geom.RayWith(origin, direction), true
+	}
+
+	// every ray through the pixel meets at the same point on the focal plane
+	fd := c.Lens.FocalDistance
+	if fd <= 0 {
+		fd = c.lookDistance
+	}
+	lx, ly := c.Lens.sample(rng)
+	focus := c.Transform.Invert().MulTuple(geom.NewPoint(c.eyeOffset+worldX*fd, worldY*fd, -fd))
+	origin := c.Transform.Invert().MulTuple(geom.NewPoint(c.eyeOffset+lx, ly, 0))
+	direction := focus.Sub(origin).Normalize()
+
+	return geom.RayWith(origin, direction), true
+}
+
+func (c Camera) projectedRay(px int, py int, ox, oy float64, rng *rand.Rand) (geom.Ray, bool) {
+	u := (float64(px) + ox) / float64(c.HSize)
+	v := (float64(py) + oy) / float64(c.VSize)
+	origin, direction, ok := c.Projection.CameraRay(u, v, float64(c.HSize)/float64(c.VSize))
+	if !ok {
+		return geom.Ray{}, false
+	}
+	// other projections have no image plane to shift, stereo only moves the eye
+	origin = origin.Add(geom.NewVector(c.eyeOffset, 0, 0))
+
+	if !c.Lens.isPinhole() {
+		// without a focal plane for every projection, rays meet at the focal distance along the lens center ray
+		fd := c.Lens.FocalDistance
+		if fd <= 0 {
+			fd = c.lookDistance
+		}
+		focus := origin.Add(direction.Normalize().Mul(fd))
+		lx, ly := c.Lens.sample(rng)
+		origin = origin.Add(geom.NewVector(lx, ly, 0))
+		direction = focus.Sub(origin)
+	}
+
+	inv := c.Transform.Invert()
+	return geom.RayWith(inv.MulTuple(origin), inv.MulTuple(direction).Normalize()), true
+}
+
+// colorForPixel combines every sample of the pixel with the reconstruction filter
+func (c Camera) colorForPixel(w *World, px, py int, rayBounces int, rng *rand.Rand) (colors.Color, int) {
+	if c.Sampling.SamplesPerPixel <= 1 && c.Sampling.Pattern == SampleGrid {
+		return c.colorAt(w, px, py, 0.5, 0.5, rayBounces, rng), 1
+	}
+
+	filter := c.Sampling.Filter.orDefault()
+	sum := colors.Black()
+	unweighted := colors.Black()
+	totalWeight := 0.0
+	offsets := c.Sampling.offsets(rng)
+	for _, o := range offsets {
+		col := c.colorAt(w, px, py, 0.5+o[0], 0.5+o[1], rayBounces, rng)
+		weight := filter.weight(o[0], o[1])
+		sum = sum.Add(col.MulBy(weight))
+		unweighted = unweighted.Add(col)
+		totalWeight += weight
+	}
 
-	return geom.RayWith(origin, direction)
+	// negative lobes, like mitchell's, can cancel out for unlucky samples
+	if totalWeight <= 0 {
+		return unweighted.MulBy(1 / float64(len(offsets))), len(offsets)
+	}
+	return sum.MulBy(1 / totalWeight), len(offsets)
 }
 
+// Render blocks until every pixel is rendered
 func (c Camera) Render(w *World, rayBounces int, numGoRoutines int) *canvas.Canvas {
 	image := canvas.NewCanvas(c.HSize, c.VSize)
 
-	wg := sync.WaitGroup{}
-	pixelsPerWorker := c.HSize * c.VSize / numGoRoutines
-	for i := 0; i < c.HSize*c.VSize; i += pixelsPerWorker {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
-			for j := i; j < i+pixelsPerWorker && j < c.HSize*c.VSize; j++ {
-				x := j % c.VSize
-				y := j / c.VSize
-
-				r := c.rayForPixel(x, y)
-				c := w.ColorAt(r, rayBounces)
-				image.SetPixel(x, y, c)
-			}
-		}(i)
+	pc, err := Render(context.Background(), w, c, rayBounces, numGoRoutines, coordinate_supplier.Asc)
+	if err != nil {
+		panic(fmt.Sprintf("render camera: %v", err))
+	}
+	for p := range pc {
+		image.SetPixel(p.X, p.Y, p.C)
 	}
-	wg.Wait()
 	return image
 }
 
@@ -89,4 +209,6 @@ type PixelInfo struct {
 	X int
 	Y int
 	C colors.Color
+	// Samples is the number of rays fired through the pixel
+	Samples int
 }
@@ -0,0 +1,39 @@
+package canvas
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"sync"
+)
+
+// Accumulator keeps the running mean of every sample added to each pixel,
+// so noisy renders converge as more passes are added
+type Accumulator struct {
+	means  []colors.Color
+	counts []int
+	width  int
+	rw     sync.RWMutex
+}
+
+func NewAccumulator(width, height int) *Accumulator {
+	return &Accumulator{
+		means:  make([]colors.Color, width*height),
+		counts: make([]int, width*height),
+		width:  width,
+	}
+}
+
+// Add folds one more sample into the pixel and returns the new mean
+func (a *Accumulator) Add(x, y int, c colors.Color) colors.Color {
+	a.rw.Lock()
+	defer a.rw.Unlock()
+	i := y*a.width + x
+	a.counts[i]++
+	a.means[i] = a.means[i].Add(c.Sub(a.means[i]).MulBy(1 / float64(a.counts[i])))
+	return a.means[i]
+}
+
+func (a *Accumulator) GetPixel(x, y int) colors.Color {
+	a.rw.RLock()
+	defer a.rw.RUnlock()
+	return a.means[y*a.width+x]
+}
@@ -0,0 +1,18 @@
+package canvas
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_Accumulator_RunningMean(t *testing.T) {
+	a := NewAccumulator(3, 2)
+
+	require.Equal(t, colors.NewColor(1, 0, 2), a.Add(2, 1, colors.NewColor(1, 0, 2)))
+	require.Equal(t, colors.NewColor(2, 1, 2), a.Add(2, 1, colors.NewColor(3, 2, 2)))
+	a.Add(2, 1, colors.NewColor(5, 0.5, 2))
+
+	require.Equal(t, colors.NewColor(3, 0.83333, 2), a.GetPixel(2, 1).RoundTo(5))
+	require.Equal(t, colors.Black(), a.GetPixel(0, 0))
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/robkau/go-raytrace/lib/colors"
 	"image"
-	gocolor "image/color"
+	"image/png"
 	"io"
 	"os"
 	"strconv"
@@ -67,27 +67,56 @@ func (c *Canvas) SetPixel(x int, y int, col colors.Color) {
 	c.pixels[y*c.width+x] = col
 }
 
+// ToImage clamps linear colors to 8 bits without tone mapping or gamma
 func (c *Canvas) ToImage() image.Image {
-	upLeft := image.Point{0, 0}
-	lowRight := image.Point{c.width, c.height}
-
-	img := image.NewRGBA(image.Rectangle{upLeft, lowRight})
-
-	for x := 0; x < c.width; x++ {
-		for y := 0; y < c.height; y++ {
-			p := c.GetPixel(x, y)
-			img.Set(x, y, gocolor.RGBA{
-				R: uint8(clamp(p.R*float64(ppmMaxColorValue), ppmMinColorValue, ppmMaxColorValue)),
-				G: uint8(clamp(p.G*float64(ppmMaxColorValue), ppmMinColorValue, ppmMaxColorValue)),
-				B: uint8(clamp(p.B*float64(ppmMaxColorValue), ppmMinColorValue, ppmMaxColorValue)),
-				A: 0xff})
-		}
+	return c.ToImageWith(LinearPipeline())
+}
+
+func (c *Canvas) ToImageWith(p Pipeline) image.Image {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+
+	img := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+	for i, px := range c.pixels {
+		r, g, b := p.to8Bit(px)
+		img.Pix[i*4] = r
+		img.Pix[i*4+1] = g
+		img.Pix[i*4+2] = b
+		img.Pix[i*4+3] = 0xff
 	}
 	return img
 }
 
+func (c *Canvas) WritePNG(w io.Writer) error {
+	return c.writePNG(w, LinearPipeline())
+}
+
+func (c *Canvas) writePNG(w io.Writer, p Pipeline) error {
+	if err := png.Encode(w, c.ToImageWith(p)); err != nil {
+		return fmt.Errorf("encode png: %w", err)
+	}
+	return nil
+}
+
+// WritePPM writes plain text PPM (P3)
+func (c *Canvas) WritePPM(w io.Writer) error {
+	return c.writePPM(w, LinearPipeline())
+}
+
+func (c *Canvas) writePPM(w io.Writer, p Pipeline) error {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	if _, err := io.WriteString(w, c.toPPMWith(p)); err != nil {
+		return fmt.Errorf("write ppm: %w", err)
+	}
+	return nil
+}
+
 func (c *Canvas) toPPM() string {
+	return c.toPPMWith(LinearPipeline())
+}
 
+func (c *Canvas) toPPMWith(pl Pipeline) string {
 	b := strings.Builder{}
 	b.WriteString(fmt.Sprintf("%s\n", ppmFileHeader))
 	b.WriteString(fmt.Sprintf("%d %d\n", c.width, c.height))
@@ -96,9 +125,10 @@ func (c *Canvas) toPPM() string {
 	lineLength := 0
 	for i, p := range c.pixels {
 
-		rc := fmt.Sprintf("%d", clamp(p.R*float64(ppmMaxColorValue), ppmMinColorValue, ppmMaxColorValue))
-		gc := fmt.Sprintf("%d", clamp(p.G*float64(ppmMaxColorValue), ppmMinColorValue, ppmMaxColorValue))
-		bc := fmt.Sprintf("%d", clamp(p.B*float64(ppmMaxColorValue), ppmMinColorValue, ppmMaxColorValue))
+		r8, g8, b8 := pl.to8Bit(p)
+		rc := strconv.Itoa(int(r8))
+		gc := strconv.Itoa(int(g8))
+		bc := strconv.Itoa(int(b8))
 		rcl := len(rc)
 		gcl := len(gc)
 		bcl := len(bc)
@@ -199,6 +229,9 @@ func CanvasFromPPMReader(r io.Reader) (*Canvas, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read bytes: %w", err)
 	}
+	if bytes.HasPrefix(ppmBytes, []byte(ppmBinaryFileHeader)) {
+		return canvasFromP6(ppmBytes)
+	}
 
 	var c *Canvas
 	var colorScale int
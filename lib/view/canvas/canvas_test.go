@@ -1,9 +1,11 @@
 package canvas
 
 import (
+	"bytes"
 	"github.com/robkau/go-raytrace/lib/colors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"image/png"
 	"strconv"
 	"strings"
 	"testing"
@@ -72,6 +74,34 @@ func Test_ToPPM_EndsWithNewLine(t *testing.T) {
 	assert.Equal(t, "\n", lastChar)
 }
 
+func Test_WritePPM_RoundTrip(t *testing.T) {
+	c := NewCanvas(5, 3)
+	c.SetPixel(0, 0, colors.NewColor(1, 0, 0))
+	c.SetPixel(4, 2, colors.NewColor(0, 0, 1))
+	b := &bytes.Buffer{}
+
+	require.NoError(t, c.WritePPM(b))
+	read, err := CanvasFromPPMReader(b)
+
+	require.NoError(t, err)
+	assert.Equal(t, c.pixels, read.pixels)
+}
+
+func Test_WritePNG(t *testing.T) {
+	c := NewCanvas(5, 3)
+	c.SetPixel(2, 1, colors.NewColor(1, 0.5, 0))
+	b := &bytes.Buffer{}
+
+	require.NoError(t, c.WritePNG(b))
+	img, err := png.Decode(b)
+
+	require.NoError(t, err)
+	assert.Equal(t, 5, img.Bounds().Dx())
+	assert.Equal(t, 3, img.Bounds().Dy())
+	r, g, bl, a := img.At(2, 1).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0x8080, 0, 0xffff}, []uint32{r, g, bl, a})
+}
+
 func Test_CanvasFromPPM_InvalidHeader(t *testing.T) {
 	ppmFile := `P32
 1 1
@@ -0,0 +1,319 @@
+package canvas
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"image"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	// registers png decoding for image.Decode
+	_ "image/png"
+)
+
+// Format is an image file format a Canvas can be encoded to and decoded from
+type Format uint8
+
+const (
+	FormatPNG Format = iota
+	FormatJPEG
+	// FormatPPM is binary PPM (P6)
+	FormatPPM
+	// FormatPPMASCII is plain text PPM (P3)
+	FormatPPMASCII
+	// FormatHDR is Radiance RGBE, keeping values above 1
+	FormatHDR
+	// FormatPFM is Portable Float Map, keeping values above 1
+	FormatPFM
+)
+
+const (
+	ppmBinaryFileHeader = "P6"
+	jpegQuality         = 95
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatPNG:
+		return "png"
+	case FormatJPEG:
+		return "jpeg"
+	case FormatPPM:
+		return "ppm"
+	case FormatPPMASCII:
+		return "ppm-ascii"
+	case FormatHDR:
+		return "hdr"
+	case FormatPFM:
+		return "pfm"
+	default:
+		return fmt.Sprintf("Format(%d)", f)
+	}
+}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range []Format{FormatPNG, FormatJPEG, FormatPPM, FormatPPMASCII, FormatHDR, FormatPFM} {
+		if s == f.String() {
+			return f, nil
+		}
+	}
+	if s == "jpg" {
+		return FormatJPEG, nil
+	}
+	return FormatPNG, fmt.Errorf("unknown image format %q", s)
+}
+
+// FormatFromExtension picks the format for a file path. .ppm files are written as binary P6
+func FormatFromExtension(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return FormatPNG, nil
+	case ".jpg", ".jpeg":
+		return FormatJPEG, nil
+	case ".ppm":
+		return FormatPPM, nil
+	case ".hdr":
+		return FormatHDR, nil
+	case ".pfm":
+		return FormatPFM, nil
+	default:
+		return FormatPNG, fmt.Errorf("unsupported image file extension %q", filepath.Ext(path))
+	}
+}
+
+func (c *Canvas) Encode(w io.Writer, f Format) error {
+	return c.EncodeWith(w, f, LinearPipeline())
+}
+
+// EncodeWith applies the pipeline to 8 bit formats. HDR and PFM keep the linear colors untouched
+func (c *Canvas) EncodeWith(w io.Writer, f Format, p Pipeline) error {
+	switch f {
+	case FormatPNG:
+		return c.writePNG(w, p)
+	case FormatJPEG:
+		return c.writeJPEG(w, p)
+	case FormatPPM:
+		return c.writeP6(w, p)
+	case FormatPPMASCII:
+		return c.writePPM(w, p)
+	case FormatHDR:
+		return c.WriteHDR(w)
+	case FormatPFM:
+		return c.WritePFM(w)
+	default:
+		return fmt.Errorf("unsupported image format %s", f)
+	}
+}
+
+// WriteFile encodes the canvas in the format picked from the file extension
+func (c *Canvas) WriteFile(path string) error {
+	return c.WriteFileWith(path, LinearPipeline())
+}
+
+func (c *Canvas) WriteFileWith(path string, p Pipeline) error {
+	format, err := FormatFromExtension(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	if err := c.EncodeWith(f, format, p); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close file: %w", err)
+	}
+	return nil
+}
+
+func (c *Canvas) WriteJPEG(w io.Writer) error {
+	return c.writeJPEG(w, LinearPipeline())
+}
+
+func (c *Canvas) writeJPEG(w io.Writer, p Pipeline) error {
+	if err := jpeg.Encode(w, c.ToImageWith(p), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return fmt.Errorf("encode jpeg: %w", err)
+	}
+	return nil
+}
+
+// WriteP6 writes binary PPM
+func (c *Canvas) WriteP6(w io.Writer) error {
+	return c.writeP6(w, LinearPipeline())
+}
+
+func (c *Canvas) writeP6(w io.Writer, pl Pipeline) error {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "%s\n%d %d\n%d\n", ppmBinaryFileHeader, c.width, c.height, ppmMaxColorValue); err != nil {
+		return fmt.Errorf("write ppm header: %w", err)
+	}
+	for _, p := range c.pixels {
+		r, g, b := pl.to8Bit(p)
+		bw.WriteByte(r)
+		bw.WriteByte(g)
+		bw.WriteByte(b)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write ppm pixels: %w", err)
+	}
+	return nil
+}
+
+// Decode reads a PNG, JPEG, PPM (P3 or P6), Radiance HDR or PFM image, sniffing the format from its content.
+// PNG and JPEG are decoded from sRGB, PPM is read as linear like the PPM writer leaves it
+func Decode(r io.Reader) (*Canvas, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, fmt.Errorf("read image header: %w", err)
+	}
+
+	if string(magic) == ppmFileHeader || string(magic) == ppmBinaryFileHeader {
+		return CanvasFromPPMReader(br)
+	}
+	if isHDR(magic) {
+		return CanvasFromHDRReader(br)
+	}
+	if isPFM(magic) {
+		return CanvasFromPFMReader(br)
+	}
+
+	img, _, err := image.Decode(br)
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	return CanvasFromImage(img), nil
+}
+
+// CanvasFromFile decodes an image file. a .zip file must hold exactly one image
+func CanvasFromFile(path string) (*Canvas, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".zip" {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("open zip reader: %w", err)
+		}
+		defer zr.Close()
+
+		if len(zr.File) != 1 {
+			return nil, fmt.Errorf("expect one file inside zip but had %d", len(zr.File))
+		}
+
+		zf, err := zr.File[0].Open()
+		if err != nil {
+			return nil, fmt.Errorf("open zip file data: %w", err)
+		}
+		defer zf.Close()
+		return Decode(zf)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open filepath: %w", err)
+	}
+	defer f.Close()
+	return Decode(f)
+}
+
+// CanvasFromImage scales 8 or 16 bit channels to 0..1 and decodes them from sRGB to linear,
+// the inverse of ToImageWith an sRGB pipeline. alpha is ignored
+func CanvasFromImage(img image.Image) *Canvas {
+	b := img.Bounds()
+	c := NewCanvas(b.Dx(), b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			c.pixels[y*c.width+x] = colors.NewColor(linearFromSRGB(float64(r)/0xffff), linearFromSRGB(float64(g)/0xffff), linearFromSRGB(float64(bl)/0xffff))
+		}
+	}
+	return c
+}
+
+func canvasFromP6(data []byte) (*Canvas, error) {
+	fields, raster, err := ppmHeaderFields(data, 4)
+	if err != nil {
+		return nil, err
+	}
+	if fields[0] != ppmBinaryFileHeader {
+		return nil, fmt.Errorf("invalid PPM header, should start with %s", ppmBinaryFileHeader)
+	}
+
+	var vals [3]int
+	for i, f := range fields[1:] {
+		vals[i], err = strconv.Atoi(f)
+		if err != nil || vals[i] < 1 {
+			return nil, fmt.Errorf("invalid ppm header value %q", f)
+		}
+	}
+	width, height, maxVal := vals[0], vals[1], vals[2]
+	if maxVal > 65535 {
+		return nil, fmt.Errorf("ppm max color value %d above 65535", maxVal)
+	}
+
+	bytesPerSample := 1
+	if maxVal > 255 {
+		bytesPerSample = 2
+	}
+	if want := width * height * 3 * bytesPerSample; len(raster) < want {
+		return nil, fmt.Errorf("ppm has %d bytes of pixel data but needs %d", len(raster), want)
+	}
+
+	sample := func(i int) float64 {
+		if bytesPerSample == 2 {
+			return float64(int(raster[2*i])<<8|int(raster[2*i+1])) / float64(maxVal)
+		}
+		return float64(raster[i]) / float64(maxVal)
+	}
+
+	c := NewCanvas(width, height)
+	for i := range c.pixels {
+		c.pixels[i] = colors.NewColor(sample(3*i), sample(3*i+1), sample(3*i+2))
+	}
+	return c, nil
+}
+
+// ppmHeaderFields splits the whitespace separated header fields, skipping comments.
+// the raster starts after the single whitespace character following the last field.
+func ppmHeaderFields(data []byte, count int) (fields []string, raster []byte, err error) {
+	i := 0
+	for len(fields) < count {
+		for i < len(data) && isPPMSpace(data[i]) {
+			i++
+		}
+		if i < len(data) && data[i] == '#' {
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		start := i
+		for i < len(data) && !isPPMSpace(data[i]) && data[i] != '#' {
+			i++
+		}
+		if start == i {
+			return nil, nil, fmt.Errorf("ppm header ended after %d fields", len(fields))
+		}
+		fields = append(fields, string(data[start:i]))
+	}
+	if i >= len(data) {
+		return nil, nil, fmt.Errorf("ppm has no pixel data")
+	}
+	return fields, data[i+1:], nil
+}
+
+func isPPMSpace(b byte) bool {
+	return bytes.IndexByte([]byte(" \t\r\n\v\f"), b) >= 0
+}
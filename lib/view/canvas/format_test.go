@@ -0,0 +1,193 @@
+package canvas
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/stretchr/testify/require"
+	"image"
+	gocolor "image/color"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func testPatternCanvas() *Canvas {
+	c := NewCanvas(4, 3)
+	c.SetPixel(0, 0, colors.NewColor(1, 0, 0))
+	c.SetPixel(1, 0, colors.NewColor(0, 1, 0))
+	c.SetPixel(2, 1, colors.NewColor(0, 0, 1))
+	c.SetPixel(3, 2, colors.NewColor(1, 1, 1))
+	c.SetPixel(0, 2, colors.NewColor(0.2, 0.4, 0.6))
+	return c
+}
+
+func Test_FormatFromExtension(t *testing.T) {
+	type args struct {
+		path   string
+		expect Format
+		err    bool
+	}
+
+	tests := []args{
+		{"out.png", FormatPNG, false},
+		{"dir/OUT.PNG", FormatPNG, false},
+		{"out.jpg", FormatJPEG, false},
+		{"out.jpeg", FormatJPEG, false},
+		{"out.ppm", FormatPPM, false},
+		{"out.gif", FormatPNG, true},
+		{"out", FormatPNG, true},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			f, err := FormatFromExtension(tt.path)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, f)
+		})
+	}
+}
+
+func Test_ParseFormat(t *testing.T) {
+	for _, f := range []Format{FormatPNG, FormatJPEG, FormatPPM, FormatPPMASCII} {
+		parsed, err := ParseFormat(f.String())
+		require.NoError(t, err)
+		require.Equal(t, f, parsed)
+	}
+
+	_, err := ParseFormat("tiff")
+	require.Error(t, err)
+}
+
+func Test_EncodeDecode_RoundTrip(t *testing.T) {
+	type args struct {
+		f     Format
+		p     Pipeline
+		delta float64
+	}
+
+	// png is read back as sRGB, ppm as linear
+	tests := []args{
+		{FormatPNG, Pipeline{Transfer: TransferSRGB}, 1. / 255},
+		{FormatPPM, LinearPipeline(), 1. / 255},
+		{FormatPPMASCII, LinearPipeline(), 1. / 255},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			c := testPatternCanvas()
+			b := &bytes.Buffer{}
+
+			require.NoError(t, c.EncodeWith(b, tt.f, tt.p))
+			read, err := Decode(b)
+
+			require.NoError(t, err)
+			require.Equal(t, c.width, read.width)
+			require.Equal(t, c.height, read.height)
+			for i := range c.pixels {
+				require.InDelta(t, c.pixels[i].R, read.pixels[i].R, tt.delta)
+				require.InDelta(t, c.pixels[i].G, read.pixels[i].G, tt.delta)
+				require.InDelta(t, c.pixels[i].B, read.pixels[i].B, tt.delta)
+			}
+		})
+	}
+}
+
+func Test_EncodeDecode_JPEG(t *testing.T) {
+	// jpeg is lossy and subsamples color, so only a flat image comes back close
+	c := newCanvasWith(16, 16, colors.NewColor(0.2, 0.4, 0.6))
+	b := &bytes.Buffer{}
+
+	require.NoError(t, c.EncodeWith(b, FormatJPEG, Pipeline{Transfer: TransferSRGB}))
+	read, err := Decode(b)
+
+	require.NoError(t, err)
+	require.Equal(t, 16, read.width)
+	for _, p := range read.pixels {
+		require.InDelta(t, 0.2, p.R, 0.02)
+		require.InDelta(t, 0.4, p.G, 0.02)
+		require.InDelta(t, 0.6, p.B, 0.02)
+	}
+}
+
+func Test_WriteP6_Header(t *testing.T) {
+	c := NewCanvas(2, 1)
+	c.SetPixel(1, 0, colors.NewColor(1, 0.5, 0))
+	b := &bytes.Buffer{}
+
+	require.NoError(t, c.WriteP6(b))
+
+	require.Equal(t, append([]byte("P6\n2 1\n255\n"), 0, 0, 0, 255, 128, 0), b.Bytes())
+}
+
+func Test_CanvasFromP6_CommentsAndWideSamples(t *testing.T) {
+	data := append([]byte("P6 # comment\n# another\n1 2\n65535\n"), 0xff, 0xff, 0x80, 0x00, 0, 0, 0, 0, 0, 0, 0xff, 0xff)
+
+	c, err := CanvasFromPPMReader(bytes.NewReader(data))
+
+	require.NoError(t, err)
+	require.Equal(t, 1, c.width)
+	require.Equal(t, 2, c.height)
+	require.Equal(t, colors.NewColor(1, float64(0x8000)/0xffff, 0), c.GetPixel(0, 0))
+	require.Equal(t, colors.NewColor(0, 0, 1), c.GetPixel(0, 1))
+}
+
+func Test_CanvasFromP6_Truncated(t *testing.T) {
+	_, err := CanvasFromPPMReader(bytes.NewReader(append([]byte("P6\n2 2\n255\n"), 1, 2, 3)))
+	require.Error(t, err)
+
+	_, err = CanvasFromPPMReader(bytes.NewReader([]byte("P6\n2 2")))
+	require.Error(t, err)
+}
+
+func Test_CanvasFromImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(10, 10, 12, 11))
+	img.Set(10, 10, gocolor.NRGBA{R: 255, G: 0, B: 51, A: 255})
+	img.Set(11, 10, gocolor.NRGBA{R: 0, G: 255, B: 0, A: 255})
+
+	c := CanvasFromImage(img)
+
+	require.Equal(t, 2, c.width)
+	require.Equal(t, 1, c.height)
+	// decoded from sRGB
+	require.Equal(t, colors.NewColor(1, 0, linearFromSRGB(0.2)), c.GetPixel(0, 0))
+	require.InDelta(t, 0.0331, c.GetPixel(0, 0).B, 1e-4)
+	require.Equal(t, colors.NewColor(0, 1, 0), c.GetPixel(1, 0))
+}
+
+func Test_CanvasFromFile(t *testing.T) {
+	dir := t.TempDir()
+	c := testPatternCanvas()
+
+	for _, name := range []string{"a.png", "a.jpg", "a.ppm"} {
+		p := filepath.Join(dir, name)
+		require.NoError(t, c.WriteFile(p))
+		read, err := CanvasFromFile(p)
+		require.NoError(t, err)
+		require.Equal(t, c.width, read.width)
+	}
+
+	// zipped png
+	zipPath := filepath.Join(dir, "a.png.zip")
+	f, err := os.Create(zipPath)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("a.png")
+	require.NoError(t, err)
+	require.NoError(t, c.EncodeWith(w, FormatPNG, Pipeline{Transfer: TransferSRGB}))
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	read, err := CanvasFromFile(zipPath)
+	require.NoError(t, err)
+	require.Equal(t, c.GetPixel(3, 2), read.GetPixel(3, 2))
+	require.InDelta(t, 0.6, read.GetPixel(0, 2).B, 1./255)
+
+	_, err = CanvasFromFile(filepath.Join(dir, "missing.png"))
+	require.Error(t, err)
+}
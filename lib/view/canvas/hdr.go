@@ -0,0 +1,291 @@
+package canvas
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	hdrFileHeader   = "#?RADIANCE"
+	hdrFormatLine   = "FORMAT=32-bit_rle_rgbe"
+	pfmColorHeader  = "PF"
+	pfmGrayHeader   = "Pf"
+	hdrMinRLEWidth  = 8
+	hdrMaxRLEWidth  = 0x7fff
+	pfmLittleEndian = -1.0
+)
+
+// WriteHDR writes Radiance RGBE without run length encoding. negative values are written as 0
+func (c *Canvas) WriteHDR(w io.Writer) error {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "%s\n%s\n\n-Y %d +X %d\n", hdrFileHeader, hdrFormatLine, c.height, c.width); err != nil {
+		return fmt.Errorf("write hdr header: %w", err)
+	}
+	for _, p := range c.pixels {
+		rgbe := toRGBE(p)
+		bw.Write(rgbe[:])
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write hdr pixels: %w", err)
+	}
+	return nil
+}
+
+// WritePFM writes little endian Portable Float Map, rows from bottom to top as the format requires
+func (c *Canvas) WritePFM(w io.Writer) error {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	if _, err := fmt.Fprintf(bw, "%s\n%d %d\n%.1f\n", pfmColorHeader, c.width, c.height, pfmLittleEndian); err != nil {
+		return fmt.Errorf("write pfm header: %w", err)
+	}
+	row := make([]byte, c.width*3*4)
+	for y := c.height - 1; y >= 0; y-- {
+		for x := 0; x < c.width; x++ {
+			p := c.pixels[y*c.width+x]
+			binary.LittleEndian.PutUint32(row[x*12:], math.Float32bits(float32(p.R)))
+			binary.LittleEndian.PutUint32(row[x*12+4:], math.Float32bits(float32(p.G)))
+			binary.LittleEndian.PutUint32(row[x*12+8:], math.Float32bits(float32(p.B)))
+		}
+		bw.Write(row)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write pfm pixels: %w", err)
+	}
+	return nil
+}
+
+// CanvasFromHDRReader reads Radiance RGBE with flat or run length encoded scanlines, in the standard -Y +X orientation
+func CanvasFromHDRReader(r io.Reader) (*Canvas, error) {
+	br := bufio.NewReader(r)
+
+	line, err := br.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "#?") {
+		return nil, fmt.Errorf("invalid hdr header, should start with #?")
+	}
+	for {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("read hdr header: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") && line != hdrFormatLine {
+			return nil, fmt.Errorf("unsupported hdr format %s", strings.TrimPrefix(line, "FORMAT="))
+		}
+	}
+
+	line, err = br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read hdr resolution: %w", err)
+	}
+	parts := strings.Fields(line)
+	if len(parts) != 4 || parts[0] != "-Y" || parts[2] != "+X" {
+		return nil, fmt.Errorf("unsupported hdr resolution line %q", strings.TrimSpace(line))
+	}
+	height, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("convert height to int: %w", err)
+	}
+	width, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, fmt.Errorf("convert width to int: %w", err)
+	}
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("invalid hdr size %dx%d", width, height)
+	}
+
+	c := NewCanvas(width, height)
+	scanline := make([]byte, width*4)
+	for y := 0; y < height; y++ {
+		if err := readHDRScanline(br, scanline); err != nil {
+			return nil, fmt.Errorf("read hdr scanline %d: %w", y, err)
+		}
+		for x := 0; x < width; x++ {
+			c.pixels[y*width+x] = fromRGBE(scanline[x*4], scanline[x*4+1], scanline[x*4+2], scanline[x*4+3])
+		}
+	}
+	return c, nil
+}
+
+// readHDRScanline fills scanline with rgbe values for each pixel
+func readHDRScanline(br *bufio.Reader, scanline []byte) error {
+	width := len(scanline) / 4
+	start, err := br.Peek(4)
+	if err != nil {
+		return err
+	}
+
+	rle := width >= hdrMinRLEWidth && width <= hdrMaxRLEWidth && start[0] == 2 && start[1] == 2 && start[2]&0x80 == 0
+	if !rle {
+		_, err := io.ReadFull(br, scanline)
+		return err
+	}
+
+	if int(start[2])<<8|int(start[3]) != width {
+		return fmt.Errorf("run length encoded scanline width does not match image width %d", width)
+	}
+	br.Discard(4)
+
+	// each channel is encoded separately
+	for ch := 0; ch < 4; ch++ {
+		for x := 0; x < width; {
+			count, err := br.ReadByte()
+			if err != nil {
+				return err
+			}
+			if count > 128 {
+				run := int(count) - 128
+				v, err := br.ReadByte()
+				if err != nil {
+					return err
+				}
+				if x+run > width {
+					return fmt.Errorf("run overflows scanline")
+				}
+				for ; run > 0; run-- {
+					scanline[x*4+ch] = v
+					x++
+				}
+			} else {
+				n := int(count)
+				if n == 0 || x+n > width {
+					return fmt.Errorf("invalid literal run length %d", n)
+				}
+				for ; n > 0; n-- {
+					v, err := br.ReadByte()
+					if err != nil {
+						return err
+					}
+					scanline[x*4+ch] = v
+					x++
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// CanvasFromPFMReader reads color (PF) or grayscale (Pf) Portable Float Maps of either byte order
+func CanvasFromPFMReader(r io.Reader) (*Canvas, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read bytes: %w", err)
+	}
+
+	fields, raster, err := ppmHeaderFields(data, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	var channels int
+	switch fields[0] {
+	case pfmColorHeader:
+		channels = 3
+	case pfmGrayHeader:
+		channels = 1
+	default:
+		return nil, fmt.Errorf("invalid PFM header, should start with %s or %s", pfmColorHeader, pfmGrayHeader)
+	}
+
+	width, err := strconv.Atoi(fields[1])
+	if err != nil || width < 1 {
+		return nil, fmt.Errorf("invalid pfm width %q", fields[1])
+	}
+	height, err := strconv.Atoi(fields[2])
+	if err != nil || height < 1 {
+		return nil, fmt.Errorf("invalid pfm height %q", fields[2])
+	}
+	scale, err := strconv.ParseFloat(fields[3], 64)
+	if err != nil || scale == 0 {
+		return nil, fmt.Errorf("invalid pfm scale %q", fields[3])
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if scale < 0 {
+		order = binary.LittleEndian
+	}
+
+	if want := width * height * channels * 4; len(raster) < want {
+		return nil, fmt.Errorf("pfm has %d bytes of pixel data but needs %d", len(raster), want)
+	}
+	sample := func(i int) float64 {
+		return float64(math.Float32frombits(order.Uint32(raster[i*4:])))
+	}
+
+	c := NewCanvas(width, height)
+	for row := 0; row < height; row++ {
+		// rows are stored bottom to top
+		y := height - 1 - row
+		for x := 0; x < width; x++ {
+			i := (row*width + x) * channels
+			if channels == 1 {
+				v := sample(i)
+				c.pixels[y*width+x] = colors.NewColor(v, v, v)
+			} else {
+				c.pixels[y*width+x] = colors.NewColor(sample(i), sample(i+1), sample(i+2))
+			}
+		}
+	}
+	return c, nil
+}
+
+func toRGBE(p colors.Color) [4]byte {
+	r := rgbeChannel(p.R)
+	g := rgbeChannel(p.G)
+	b := rgbeChannel(p.B)
+	v := math.Max(math.Max(r, g), b)
+	if v == 0 {
+		return [4]byte{}
+	}
+
+	m, e := math.Frexp(v)
+	if e < -127 {
+		// too dark for the exponent byte, an exponent of 0 is black
+		return [4]byte{}
+	}
+	scale := m * 256 / v
+	if e > 127 {
+		// too bright for the exponent byte, write the brightest color of the same hue
+		e = 127
+		scale = 255 / v
+	}
+	return [4]byte{byte(r * scale), byte(g * scale), byte(b * scale), byte(e + 128)}
+}
+
+// rgbeChannel is 0 for negative, NaN and infinite values, which rgbe cannot store
+func rgbeChannel(v float64) float64 {
+	if v <= 0 || math.IsNaN(v) || math.IsInf(v, 1) {
+		return 0
+	}
+	return v
+}
+
+func fromRGBE(r, g, b, e byte) colors.Color {
+	if e == 0 {
+		return colors.NewColor(0, 0, 0)
+	}
+	f := math.Ldexp(1, int(e)-(128+8))
+	return colors.NewColor((float64(r)+0.5)*f, (float64(g)+0.5)*f, (float64(b)+0.5)*f)
+}
+
+func isHDR(magic []byte) bool {
+	return bytes.HasPrefix(magic, []byte("#?"))
+}
+
+func isPFM(magic []byte) bool {
+	return bytes.HasPrefix(magic, []byte(pfmColorHeader)) || bytes.HasPrefix(magic, []byte(pfmGrayHeader))
+}
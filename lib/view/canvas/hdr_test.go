@@ -0,0 +1,175 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/stretchr/testify/require"
+	"math"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func testHDRCanvas() *Canvas {
+	c := NewCanvas(3, 2)
+	c.SetPixel(0, 0, colors.NewColor(1.9, 1.4, 1.4))
+	c.SetPixel(1, 0, colors.NewColor(0.001, 0.5, 250))
+	c.SetPixel(2, 0, colors.NewColor(1, 1, 1))
+	c.SetPixel(0, 1, colors.NewColor(12345, 0, 0.25))
+	return c
+}
+
+func Test_HDR_RoundTrip(t *testing.T) {
+	c := testHDRCanvas()
+	b := &bytes.Buffer{}
+
+	require.NoError(t, c.Encode(b, FormatHDR))
+	read, err := Decode(b)
+
+	require.NoError(t, err)
+	require.Equal(t, 3, read.width)
+	require.Equal(t, 2, read.height)
+	for i, p := range c.pixels {
+		// rgbe shares one exponent, so precision is relative to the brightest channel
+		tolerance := math.Max(math.Max(p.R, p.G), p.B) / 128
+		require.InDelta(t, p.R, read.pixels[i].R, tolerance)
+		require.InDelta(t, p.G, read.pixels[i].G, tolerance)
+		require.InDelta(t, p.B, read.pixels[i].B, tolerance)
+	}
+}
+
+func Test_ToRGBE_NonFinite(t *testing.T) {
+	type args struct {
+		c      colors.Color
+		expect colors.Color
+	}
+
+	tests := []args{
+		{colors.NewColor(math.NaN(), 0.5, 0.5), colors.NewColor(0, 0.5, 0.5)},
+		{colors.NewColor(0.5, math.Inf(1), 0.5), colors.NewColor(0.5, 0, 0.5)},
+		{colors.NewColor(0.5, 0.5, math.Inf(-1)), colors.NewColor(0.5, 0.5, 0)},
+		{colors.NewColor(-2, 0.5, 0.5), colors.NewColor(0, 0.5, 0.5)},
+		{colors.NewColor(math.NaN(), math.Inf(1), -1), colors.Black()},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			e := toRGBE(tt.c)
+			read := fromRGBE(e[0], e[1], e[2], e[3])
+			require.InDelta(t, tt.expect.R, read.R, 1./128)
+			require.InDelta(t, tt.expect.G, read.G, 1./128)
+			require.InDelta(t, tt.expect.B, read.B, 1./128)
+		})
+	}
+}
+
+func Test_ToRGBE_OutOfRange(t *testing.T) {
+	// too bright keeps the hue at the largest exponent instead of wrapping around to a dark color
+	e := toRGBE(colors.NewColor(1e39, 5e38, 0))
+	require.Equal(t, [4]byte{255, 127, 0, 255}, e)
+	read := fromRGBE(e[0], e[1], e[2], e[3])
+	require.Greater(t, read.R, 1e38)
+
+	require.Equal(t, [4]byte{}, toRGBE(colors.NewColor(1e-39, 1e-39, 1e-39)))
+}
+
+func Test_PFM_RoundTrip(t *testing.T) {
+	c := testHDRCanvas()
+	b := &bytes.Buffer{}
+
+	require.NoError(t, c.Encode(b, FormatPFM))
+	read, err := Decode(b)
+
+	require.NoError(t, err)
+	for i, p := range c.pixels {
+		require.Equal(t, float64(float32(p.R)), read.pixels[i].R)
+		require.Equal(t, float64(float32(p.G)), read.pixels[i].G)
+		require.Equal(t, float64(float32(p.B)), read.pixels[i].B)
+	}
+}
+
+func Test_WritePFM_BottomRowFirst(t *testing.T) {
+	c := NewCanvas(1, 2)
+	c.SetPixel(0, 1, colors.NewColor(2, 0, 0))
+	b := &bytes.Buffer{}
+
+	require.NoError(t, c.WritePFM(b))
+
+	header := "PF\n1 2\n-1.0\n"
+	require.Equal(t, header, b.String()[:len(header)])
+	require.Equal(t, float32(2), math.Float32frombits(binary.LittleEndian.Uint32(b.Bytes()[len(header):])))
+}
+
+func Test_CanvasFromPFM_BigEndianGrayscale(t *testing.T) {
+	data := []byte("Pf\n2 1\n1.0\n")
+	for _, v := range []float32{0.5, 3} {
+		sample := make([]byte, 4)
+		binary.BigEndian.PutUint32(sample, math.Float32bits(v))
+		data = append(data, sample...)
+	}
+
+	c, err := CanvasFromPFMReader(bytes.NewReader(data))
+
+	require.NoError(t, err)
+	require.Equal(t, colors.NewColor(0.5, 0.5, 0.5), c.GetPixel(0, 0))
+	require.Equal(t, colors.NewColor(3, 3, 3), c.GetPixel(1, 0))
+}
+
+func Test_CanvasFromHDR_RunLengthEncoded(t *testing.T) {
+	data := []byte("#?RADIANCE\n# made by hand\nFORMAT=32-bit_rle_rgbe\n\n-Y 1 +X 8\n")
+	// scanline header for width 8
+	data = append(data, 2, 2, 0, 8)
+	// red: run of 8
+	data = append(data, 128+8, 128)
+	// green: 8 literal values
+	data = append(data, 8, 0, 16, 32, 64, 128, 192, 224, 255)
+	// blue: run of 4 then 4 literals
+	data = append(data, 128+4, 0, 4, 1, 2, 3, 4)
+	// exponent: run of 8, value 129 means values are scaled by 2/256
+	data = append(data, 128+8, 129)
+
+	c, err := CanvasFromHDRReader(bytes.NewReader(data))
+
+	require.NoError(t, err)
+	require.Equal(t, 8, c.width)
+	greens := []float64{0, 16, 32, 64, 128, 192, 224, 255}
+	blues := []float64{0, 0, 0, 0, 1, 2, 3, 4}
+	for x := 0; x < 8; x++ {
+		p := c.GetPixel(x, 0)
+		require.Equal(t, (128+0.5)*2/256, p.R)
+		require.Equal(t, (greens[x]+0.5)*2/256, p.G)
+		require.Equal(t, (blues[x]+0.5)*2/256, p.B)
+	}
+}
+
+func Test_CanvasFromHDR_Invalid(t *testing.T) {
+	tests := []string{
+		"P3\n1 1\n255\n",
+		"#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n\x00\x00\x00\x00",
+		"#?RADIANCE\n\n+Y 1 +X 1\n\x00\x00\x00\x00",
+		"#?RADIANCE\n\n-Y 2 +X 1\n\x00\x00\x00\x00",
+		// run overflows the scanline
+		"#?RADIANCE\n\n-Y 1 +X 8\n\x02\x02\x00\x08\x89\x00",
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			_, err := CanvasFromHDRReader(bytes.NewReader([]byte(tt)))
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_CanvasFromFile_HDR(t *testing.T) {
+	c := testHDRCanvas()
+	dir := t.TempDir()
+
+	for _, name := range []string{"a.hdr", "a.pfm"} {
+		p := filepath.Join(dir, name)
+		require.NoError(t, c.WriteFile(p))
+		read, err := CanvasFromFile(p)
+		require.NoError(t, err)
+		require.InDelta(t, 1.9, read.GetPixel(0, 0).R, 0.02)
+	}
+}
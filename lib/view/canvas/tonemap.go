@@ -0,0 +1,133 @@
+package canvas
+
+import (
+	"fmt"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"math"
+)
+
+// ToneMapF maps a linear color of any brightness into the 0 to 1 range
+type ToneMapF func(c colors.Color) colors.Color
+
+// TransferF encodes one linear channel in 0 to 1 for display
+type TransferF func(v float64) float64
+
+// Pipeline turns linear canvas colors into display colors: exposure, then tone mapping, then the transfer function.
+// the zero value clamps linear values without any exposure change, which is how canvases were always exported.
+type Pipeline struct {
+	// Exposure in stops, each one doubles brightness
+	Exposure float64
+	// ToneMap defaults to ToneMapClamp
+	ToneMap ToneMapF
+	// Transfer defaults to TransferLinear
+	Transfer TransferF
+}
+
+// LinearPipeline clamps linear values, the same as ToImage and the PPM writer
+func LinearPipeline() Pipeline {
+	return Pipeline{ToneMap: ToneMapClamp, Transfer: TransferLinear}
+}
+
+func (p Pipeline) Apply(c colors.Color) colors.Color {
+	if p.Exposure != 0 {
+		c = c.MulBy(math.Exp2(p.Exposure))
+	}
+
+	tm := p.ToneMap
+	if tm == nil {
+		tm = ToneMapClamp
+	}
+	c = ToneMapClamp(tm(c))
+
+	if p.Transfer != nil {
+		c = colors.NewColor(p.Transfer(c.R), p.Transfer(c.G), p.Transfer(c.B))
+	}
+	return c
+}
+
+// to8Bit applies the pipeline and rounds each channel to 0..255
+func (p Pipeline) to8Bit(c colors.Color) (r, g, b uint8) {
+	c = p.Apply(c)
+	return uint8(clamp(c.R*float64(ppmMaxColorValue), ppmMinColorValue, ppmMaxColorValue)),
+		uint8(clamp(c.G*float64(ppmMaxColorValue), ppmMinColorValue, ppmMaxColorValue)),
+		uint8(clamp(c.B*float64(ppmMaxColorValue), ppmMinColorValue, ppmMaxColorValue))
+}
+
+func ToneMapClamp(c colors.Color) colors.Color {
+	return colors.NewColor(clamp01(c.R), clamp01(c.G), clamp01(c.B))
+}
+
+// ToneMapReinhard compresses each channel with c/(1+c), so nothing ever clips
+func ToneMapReinhard(c colors.Color) colors.Color {
+	f := func(v float64) float64 {
+		v = math.Max(v, 0)
+		return v / (1 + v)
+	}
+	return colors.NewColor(f(c.R), f(c.G), f(c.B))
+}
+
+// ToneMapACESFilmic is Krzysztof Narkowicz's fit of the ACES filmic curve
+// https://knarkowicz.wordpress.com/2016/01/06/aces-filmic-tone-mapping-curve/
+func ToneMapACESFilmic(c colors.Color) colors.Color {
+	const (
+		a  = 2.51
+		b  = 0.03
+		cc = 2.43
+		d  = 0.59
+		e  = 0.14
+	)
+	f := func(v float64) float64 {
+		v = math.Max(v, 0)
+		return clamp01((v * (a*v + b)) / (v*(cc*v+d) + e))
+	}
+	return colors.NewColor(f(c.R), f(c.G), f(c.B))
+}
+
+func TransferLinear(v float64) float64 {
+	return v
+}
+
+// TransferSRGB is the piecewise sRGB encoding from IEC 61966-2-1
+func TransferSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// linearFromSRGB undoes TransferSRGB
+func linearFromSRGB(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// ToneMaps are the named tone mapping operators, in the order the scene browser cycles through them
+var ToneMaps = []struct {
+	Name string
+	F    ToneMapF
+}{
+	{"clamp", ToneMapClamp},
+	{"reinhard", ToneMapReinhard},
+	{"aces", ToneMapACESFilmic},
+}
+
+func ParseToneMap(name string) (ToneMapF, error) {
+	for _, tm := range ToneMaps {
+		if tm.Name == name {
+			return tm.F, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown tone map %q", name)
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 || math.IsNaN(v) {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
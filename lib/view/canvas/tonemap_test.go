@@ -0,0 +1,105 @@
+package canvas
+
+import (
+	"bytes"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func Test_Pipeline_ZeroValueClamps(t *testing.T) {
+	p := Pipeline{}
+
+	require.Equal(t, colors.NewColor(1, 0.5, 0), p.Apply(colors.NewColor(1.9, 0.5, -0.3)))
+	require.Equal(t, LinearPipeline().Apply(colors.NewColor(1.9, 0.5, -0.3)), p.Apply(colors.NewColor(1.9, 0.5, -0.3)))
+}
+
+func Test_Pipeline_Exposure(t *testing.T) {
+	require.Equal(t, colors.NewColor(0.5, 0.2, 1), Pipeline{Exposure: 1}.Apply(colors.NewColor(0.25, 0.1, 0.75)))
+	require.Equal(t, colors.NewColor(0.125, 0.05, 0.375), Pipeline{Exposure: -1}.Apply(colors.NewColor(0.25, 0.1, 0.75)))
+}
+
+func Test_ToneMapReinhard(t *testing.T) {
+	require.Equal(t, colors.NewColor(0, 0.5, 0.9), ToneMapReinhard(colors.NewColor(0, 1, 9)))
+	require.Equal(t, colors.NewColor(0, 0, 0), ToneMapReinhard(colors.NewColor(-1, 0, 0)))
+}
+
+func Test_ToneMapACESFilmic(t *testing.T) {
+	require.Equal(t, 0.0, ToneMapACESFilmic(colors.NewColor(0, 0, 0)).R)
+	require.InDelta(t, 0.8, ToneMapACESFilmic(colors.NewColor(1, 1, 1)).R, 0.01)
+	require.Equal(t, 1.0, ToneMapACESFilmic(colors.NewColor(100, 100, 100)).R)
+
+	// never darker for a brighter input
+	last := 0.0
+	for i := 0; i < 100; i++ {
+		v := ToneMapACESFilmic(colors.NewColor(float64(i)/10, 0, 0)).R
+		require.GreaterOrEqual(t, v, last)
+		last = v
+	}
+}
+
+func Test_TransferSRGB(t *testing.T) {
+	type args struct {
+		in     float64
+		expect float64
+	}
+
+	tests := []args{
+		{0, 0},
+		{1, 1},
+		{0.0031308, 0.04044994},
+		{0.5, 0.73535698},
+		{0.2140, 0.5},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			require.InDelta(t, tt.expect, TransferSRGB(tt.in), 1e-4)
+			require.InDelta(t, tt.in, linearFromSRGB(tt.expect), 1e-4)
+		})
+	}
+}
+
+func Test_ToImageWith_SRGB(t *testing.T) {
+	c := newCanvasWith(1, 1, colors.NewColor(0.2140, 0.2140, 0.2140))
+
+	linear := c.ToImage()
+	srgb := c.ToImageWith(Pipeline{Transfer: TransferSRGB})
+
+	r, _, _, _ := linear.At(0, 0).RGBA()
+	require.Equal(t, uint32(55), r>>8)
+	r, _, _, _ = srgb.At(0, 0).RGBA()
+	require.Equal(t, uint32(127), r>>8)
+}
+
+func Test_EncodeWith_HDRStaysLinear(t *testing.T) {
+	c := newCanvasWith(1, 1, colors.NewColor(4, 2, 1))
+	b := &bytes.Buffer{}
+
+	require.NoError(t, c.EncodeWith(b, FormatPFM, Pipeline{Exposure: 3, ToneMap: ToneMapReinhard, Transfer: TransferSRGB}))
+	read, err := Decode(b)
+
+	require.NoError(t, err)
+	require.Equal(t, colors.NewColor(4, 2, 1), read.GetPixel(0, 0))
+}
+
+func Test_EncodeWith_AppliesPipeline(t *testing.T) {
+	c := newCanvasWith(1, 1, colors.NewColor(1, 3, 0))
+	b := &bytes.Buffer{}
+
+	require.NoError(t, c.EncodeWith(b, FormatPPM, Pipeline{ToneMap: ToneMapReinhard}))
+
+	require.Equal(t, append([]byte("P6\n1 1\n255\n"), 128, 191, 0), b.Bytes())
+}
+
+func Test_ParseToneMap(t *testing.T) {
+	for _, tm := range ToneMaps {
+		f, err := ParseToneMap(tm.Name)
+		require.NoError(t, err)
+		require.NotNil(t, f)
+	}
+
+	_, err := ParseToneMap("filmic")
+	require.Error(t, err)
+}
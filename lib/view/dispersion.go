@@ -0,0 +1,44 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+)
+
+// wavelengthBand is a slice of the spectrum traced with one wavelength, weighted by how much of each color it carries
+type wavelengthBand struct {
+	wavelength float64
+	weight     colors.Color
+}
+
+// spectralBands split white light from violet to red. the weights of each color add up to 1
+var spectralBands = []wavelengthBand{
+	{wavelength: 450, weight: colors.NewColor(0, 0, 0.55)},
+	{wavelength: 490, weight: colors.NewColor(0, 0.25, 0.35)},
+	{wavelength: 530, weight: colors.NewColor(0, 0.45, 0.1)},
+	{wavelength: 570, weight: colors.NewColor(0.25, 0.3, 0)},
+	{wavelength: 610, weight: colors.NewColor(0.4, 0, 0)},
+	{wavelength: 650, weight: colors.NewColor(0.35, 0, 0)},
+}
+
+// isDispersive reports if light refracted at the hit bends differently by wavelength
+func isDispersive(c shapes.IntersectionComputed) bool {
+	return (c.Medium != nil && materials.IsDispersive(c.Medium.GetMaterial())) ||
+		(c.NextMedium != nil && materials.IsDispersive(c.NextMedium.GetMaterial()))
+}
+
+// refractiveRatio is n1 / n2 at the hit for light of a wavelength, 0 is white light
+func refractiveRatio(c shapes.IntersectionComputed, wavelength float64) float64 {
+	if wavelength == 0 {
+		return c.N1 / c.N2
+	}
+	return refractiveIndexAt(c.Medium, wavelength) / refractiveIndexAt(c.NextMedium, wavelength)
+}
+
+func refractiveIndexAt(s shapes.Shape, wavelength float64) float64 {
+	if s == nil {
+		return 1
+	}
+	return materials.RefractiveIndexAt(s.GetMaterial(), wavelength)
+}
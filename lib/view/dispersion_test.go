@@ -0,0 +1,99 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/patterns"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// prismWorld is a clear glass ball under an environment that is white toward +x and black toward -x
+func prismWorld(abbe float64) *World {
+	w := NewWorld()
+	w.SetEnvironment(NewPatternEnvironment(patterns.NewStripePattern(patterns.NewSolidColorPattern(colors.White()), patterns.NewSolidColorPattern(colors.Black()))))
+	s := shapes.NewSphere()
+	m := s.GetMaterial()
+	m.Color = colors.Black()
+	m.Ambient = 0
+	m.Diffuse = 0
+	m.Specular = 0
+	m.Transparency = 1
+	m.RefractiveIndex = 1.5
+	m.AbbeNumber = abbe
+	s.SetMaterial(m)
+	w.AddObject(s)
+	return w
+}
+
+// fringe finds the ray through the ball that sees the most color, and how much
+func fringe(w *World) (geom.Ray, float64) {
+	var best geom.Ray
+	most := 0.0
+	for x := -0.95; x <= 0.95; x += 0.01 {
+		r := geom.RayWith(geom.NewPoint(x, 0, -5), geom.NewVector(0, 0, 1))
+		c := w.ColorAt(r, 4)
+		if d := math.Abs(c.R - c.B); d > most {
+			best, most = r, d
+		}
+	}
+	return best, most
+}
+
+func Test_SpectralBands_AddUpToWhite(t *testing.T) {
+	sum := colors.Black()
+	for i, b := range spectralBands {
+		sum = sum.Add(b.weight)
+		if i > 0 {
+			require.Greater(t, b.wavelength, spectralBands[i-1].wavelength)
+		}
+	}
+	require.Equal(t, colors.White(), sum.RoundTo(5))
+}
+
+func Test_RefractiveRatio_ByWavelength(t *testing.T) {
+	w := prismWorld(20)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+	xs := w.Intersect(r)
+	c := xs.I[0].Compute(r, xs)
+
+	require.True(t, isDispersive(c))
+	require.Equal(t, 1/1.5, refractiveRatio(c, 0))
+	require.InDelta(t, 1/1.5, refractiveRatio(c, materials.WavelengthD), 1e-12)
+	// blue bends more than red going into the glass
+	require.Less(t, refractiveRatio(c, 450), refractiveRatio(c, 650))
+
+	clear := prismWorld(0).Intersect(r)
+	require.False(t, isDispersive(clear.I[0].Compute(r, clear)))
+}
+
+func Test_Dispersion_SplitsColors(t *testing.T) {
+	_, clear := fringe(prismWorld(0))
+	require.Less(t, clear, 1e-9)
+
+	_, split := fringe(prismWorld(20))
+	require.Greater(t, split, 0.1)
+}
+
+func Test_Dispersion_PathTracerMatchesWhitted(t *testing.T) {
+	w := prismWorld(20)
+	r, _ := fringe(w)
+	expected := w.ColorAt(r, 4)
+
+	p := NewPathTracer()
+	rng := rand.New(rand.NewSource(1))
+	sum := colors.Black()
+	n := 6000
+	for i := 0; i < n; i++ {
+		sum = sum.Add(p.ColorAt(w, r, 4, rng))
+	}
+	mean := sum.MulBy(1 / float64(n))
+
+	require.InDelta(t, expected.R, mean.R, 0.05)
+	require.InDelta(t, expected.G, mean.G, 0.05)
+	require.InDelta(t, expected.B, mean.B, 0.05)
+}
@@ -0,0 +1,114 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"math"
+	"math/rand"
+)
+
+// whittedEmitterSamples is how many shadow rays the whitted integrator sends toward emissive shapes per hit
+const whittedEmitterSamples = 16
+
+// emitter is a primitive with an emissive material, sampled through the sphere around its world bounds
+type emitter struct {
+	s      shapes.Shape
+	center geom.Tuple
+	radius float64
+}
+
+// collectEmitters finds the emissive primitives in the shape tree.
+// shapes with infinite bounds can't be aimed at, they are only found by rays that hit them
+func collectEmitters(s shapes.Shape) []emitter {
+	if g, ok := s.(shapes.Group); ok {
+		var es []emitter
+		for _, c := range g.GetChildren() {
+			es = append(es, collectEmitters(c)...)
+		}
+		return es
+	}
+
+	if !materials.IsEmissive(s.GetMaterial()) {
+		return nil
+	}
+	box := shapes.WorldBoundsOf(s)
+	if !isFinite(box.Min) || !isFinite(box.Max) {
+		return nil
+	}
+	return []emitter{{
+		s:      s,
+		center: box.Center(),
+		radius: box.Max.Sub(box.Min).Mag() / 2,
+	}}
+}
+
+func isFinite(t geom.Tuple) bool {
+	return !math.IsInf(t.X, 0) && !math.IsInf(t.Y, 0) && !math.IsInf(t.Z, 0) &&
+		!math.IsNaN(t.X) && !math.IsNaN(t.Y) && !math.IsNaN(t.Z)
+}
+
+// sampleDirection picks a direction from p toward the bounding sphere of the emitter, with its solid angle density
+func (e emitter) sampleDirection(p geom.Tuple, rng *rand.Rand) (geom.Tuple, float64) {
+	toCenter := e.center.Sub(p)
+	dist := toCenter.Mag()
+	if dist <= e.radius {
+		// inside the bounds, any direction could reach the emitter
+		return geom.SampleSphere(uniform(rng), uniform(rng)), 1 / (4 * math.Pi)
+	}
+
+	// uniform in the cone that holds the bounding sphere
+	cosMax := math.Sqrt(1 - e.radius*e.radius/(dist*dist))
+	n := toCenter.Div(dist)
+	t, b := geom.OrthonormalBasis(n)
+	local := geom.SampleCone(uniform(rng), uniform(rng), cosMax)
+	return geom.ToBasis(local, t, b, n), 1 / (2 * math.Pi * (1 - cosMax))
+}
+
+// uniform falls back to the shared source for callers without an rng, renders always pass the worker rng
+func uniform(rng *rand.Rand) float64 {
+	if rng == nil {
+		return rand.Float64()
+	}
+	return rng.Float64()
+}
+
+// emittedLight estimates the light reflected from emissive shapes with shadow rays toward random emitters
+func (w *World) emittedLight(c shapes.IntersectionComputed, m materials.Material, samples int, rng *rand.Rand) colors.Color {
+	if len(w.emitters) == 0 || samples < 1 {
+		return colors.Black()
+	}
+	if m.Model == materials.ModelPhong {
+		if m.Diffuse == 0 {
+			return colors.Black()
+		}
+		// phong highlights only come from lights
+		m.Specular = 0
+	}
+	bsdf := shapes.NewBSDF(m, c.Object, c.OverPoint, c.Eyev, c.Normalv)
+
+	sum := colors.Black()
+	for i := 0; i < samples; i++ {
+		e := w.emitters[int(uniform(rng)*float64(len(w.emitters)))%len(w.emitters)]
+		direction, pdf := e.sampleDirection(c.OverPoint, rng)
+		cos := direction.Dot(c.Normalv)
+		if cos <= 0 {
+			continue
+		}
+		hit, ok := w.closestHit(geom.RayWith(c.OverPoint, direction))
+		if !ok || hit.O.Id() != e.s.Id() {
+			// missed the emitter, or something is in the way
+			continue
+		}
+		sum = sum.Add(bsdf.Eval(direction).Mul(hit.O.GetMaterial().Emission).MulBy(cos / pdf))
+	}
+
+	// picking one of n emitters weighs each by n
+	return sum.MulBy(float64(len(w.emitters)) / float64(samples))
+}
+
+// isSampledEmitter reports if light from the shape is already gathered by emittedLight
+func (w *World) isSampledEmitter(s shapes.Shape) bool {
+	return w.emitterIds[s.Id()]
+}
@@ -0,0 +1,113 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func newEmissiveSphere(at geom.Tuple, emission colors.Color) shapes.Shape {
+	s := shapes.NewSphere()
+	s.SetTransform(geom.Translate(at.X, at.Y, at.Z))
+	m := materials.NewMaterial()
+	m.Emission = emission
+	s.SetMaterial(m)
+	return s
+}
+
+func Test_CollectEmitters(t *testing.T) {
+	lamp := newEmissiveSphere(geom.NewPoint(1, 0, 0), colors.White())
+	g := shapes.NewGroup()
+	g.SetTransform(geom.Translate(0, 2, 0))
+	g.AddChild(lamp)
+	g.AddChild(shapes.NewSphere())
+	glowingFloor := shapes.NewPlane()
+	m := materials.NewMaterial()
+	m.Emission = colors.White()
+	glowingFloor.SetMaterial(m)
+
+	w := NewWorld()
+	w.AddObject(g)
+	w.AddObject(glowingFloor)
+
+	require.Len(t, w.emitters, 1)
+	require.Equal(t, lamp, w.emitters[0].s)
+	require.Equal(t, geom.NewPoint(1, 2, 0), w.emitters[0].center)
+	require.InDelta(t, math.Sqrt(3), w.emitters[0].radius, 1e-9)
+	require.True(t, w.isSampledEmitter(lamp))
+	require.False(t, w.isSampledEmitter(glowingFloor))
+
+	w.Divide(4)
+	require.Len(t, w.emitters, 1)
+}
+
+func Test_Whitted_SeesEmission(t *testing.T) {
+	w := NewWorld()
+	w.AddObject(newEmissiveSphere(geom.ZeroPoint(), colors.NewColor(1, 0.5, 0)))
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	require.Equal(t, colors.NewColor(1, 0.5, 0), w.ColorAt(r, 3))
+}
+
+// a sphere of radiance L and radius r lights the point straight below at distance d like a Phong light of L*(r/d)^2
+func floorUnderEmitter(t *testing.T, occluded bool) (*World, shapes.IntersectionComputed) {
+	w := NewWorld()
+	floor := shapes.NewPlane()
+	w.AddObject(floor)
+	w.AddObject(newEmissiveSphere(geom.NewPoint(0, 5, 0), colors.White()))
+	if occluded {
+		blocker := shapes.NewSphere()
+		blocker.SetTransform(geom.Translate(0, 2.5, 0))
+		w.AddObject(blocker)
+	}
+
+	r := geom.RayWith(geom.NewPoint(0, 0.5, -0.5), geom.NewVector(0, -math.Sqrt2/2, math.Sqrt2/2))
+	xs := w.Intersect(r)
+	i, ok := xs.Hit()
+	require.True(t, ok)
+	return w, i.Compute(r, xs)
+}
+
+func Test_EmittedLight(t *testing.T) {
+	w, c := floorUnderEmitter(t, false)
+
+	col := w.emittedLight(c, c.Object.GetMaterial(), 20000, rand.New(rand.NewSource(1)))
+
+	require.InDelta(t, 0.9/25, col.R, 0.002)
+}
+
+func Test_EmittedLight_Occluded(t *testing.T) {
+	w, c := floorUnderEmitter(t, true)
+
+	col := w.emittedLight(c, c.Object.GetMaterial(), 1000, rand.New(rand.NewSource(1)))
+
+	require.Equal(t, colors.Black(), col)
+}
+
+func Test_PathTracer_EmitterNotCountedTwice(t *testing.T) {
+	w, _ := floorUnderEmitter(t, false)
+	r := geom.RayWith(geom.NewPoint(0, 0.5, -0.5), geom.NewVector(0, -math.Sqrt2/2, math.Sqrt2/2))
+	rng := rand.New(rand.NewSource(1))
+
+	// diffuse bounces that hit the emitter were already counted by next event estimation
+	sum := colors.Black()
+	n := 20000
+	for i := 0; i < n; i++ {
+		sum = sum.Add(NewPathTracer().ColorAt(w, r, 1, rng))
+	}
+
+	require.InDelta(t, 0.9/25, sum.R/float64(n), 0.003)
+}
+
+func Test_PathTracer_SeesEmission(t *testing.T) {
+	w := NewWorld()
+	w.AddObject(newEmissiveSphere(geom.ZeroPoint(), colors.NewColor(1, 0.5, 0)))
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	require.Equal(t, colors.NewColor(1, 0.5, 0), NewPathTracer().ColorAt(w, r, 0, rand.New(rand.NewSource(1))))
+}
@@ -0,0 +1,279 @@
+package view
+
+import (
+	"fmt"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/patterns"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/robkau/go-raytrace/lib/view/canvas"
+	"math"
+	"math/rand"
+)
+
+// Environment is the light arriving from infinitely far away, seen by rays that miss everything in the world
+type Environment interface {
+	// ColorAt is the light arriving from direction d
+	ColorAt(d geom.Tuple) colors.Color
+	// Sample maps u, v in [0, 1) to a direction, with its density over the sphere of directions
+	Sample(u, v float64) (geom.Tuple, float64)
+	// Pdf is the density Sample picks direction d with
+	Pdf(d geom.Tuple) float64
+}
+
+// ConstantEnvironment is the same color in every direction, like an overcast sky
+type ConstantEnvironment struct {
+	Color colors.Color
+}
+
+func NewConstantEnvironment(c colors.Color) ConstantEnvironment {
+	return ConstantEnvironment{Color: c}
+}
+
+func (e ConstantEnvironment) ColorAt(geom.Tuple) colors.Color {
+	return e.Color
+}
+
+func (e ConstantEnvironment) Sample(u, v float64) (geom.Tuple, float64) {
+	return geom.SampleSphere(u, v), 1 / (4 * math.Pi)
+}
+
+func (e ConstantEnvironment) Pdf(geom.Tuple) float64 {
+	return 1 / (4 * math.Pi)
+}
+
+// PatternEnvironment looks up a pattern on the unit cube around the origin, so a CubeMapPattern works as a skybox.
+// the pattern transform turns the environment
+type PatternEnvironment struct {
+	Pattern patterns.Pattern
+}
+
+func NewPatternEnvironment(p patterns.Pattern) PatternEnvironment {
+	return PatternEnvironment{Pattern: p}
+}
+
+func (e PatternEnvironment) ColorAt(d geom.Tuple) colors.Color {
+	m := math.Max(math.Abs(d.X), math.Max(math.Abs(d.Y), math.Abs(d.Z)))
+	if m == 0 {
+		return colors.Black()
+	}
+	p := geom.NewPoint(d.X/m, d.Y/m, d.Z/m)
+	return patterns.ColorAtShape(e.Pattern, func(t geom.Tuple) geom.Tuple { return t }, p)
+}
+
+// Sample is uniform, patterns have no brightness map to follow
+func (e PatternEnvironment) Sample(u, v float64) (geom.Tuple, float64) {
+	return geom.SampleSphere(u, v), 1 / (4 * math.Pi)
+}
+
+func (e PatternEnvironment) Pdf(geom.Tuple) float64 {
+	return 1 / (4 * math.Pi)
+}
+
+// ImageEnvironment is an equirectangular image wrapped around the world, laid out like patterns.SphericalMap.
+// directions are sampled in proportion to the brightness of the image, so small bright lights like the sun are found
+type ImageEnvironment struct {
+	canvas *canvas.Canvas
+	width  int
+	height int
+	// rows picks a row by its total brightness, then columns picks a pixel within that row
+	rows    distribution1D
+	columns []distribution1D
+}
+
+func NewImageEnvironment(c *canvas.Canvas) *ImageEnvironment {
+	w, h := c.GetSize()
+	e := &ImageEnvironment{
+		canvas:  c,
+		width:   w,
+		height:  h,
+		columns: make([]distribution1D, h),
+	}
+
+	rowWeights := make([]float64, h)
+	for y := 0; y < h; y++ {
+		// rows near the poles cover less of the sphere
+		sinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(h))
+		weights := make([]float64, w)
+		for x := 0; x < w; x++ {
+			weights[x] = math.Max(0, c.GetPixel(x, y).Luminance()) * sinTheta
+		}
+		e.columns[y] = newDistribution1D(weights)
+		rowWeights[y] = e.columns[y].total
+	}
+	e.rows = newDistribution1D(rowWeights)
+	return e
+}
+
+// NewImageEnvironmentFromFile loads an equirectangular PNG, JPEG or PPM image. zipped files are unzipped first
+func NewImageEnvironmentFromFile(path string) (*ImageEnvironment, error) {
+	c, err := canvas.CanvasFromFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load environment image: %w", err)
+	}
+	return NewImageEnvironment(c), nil
+}
+
+func (e *ImageEnvironment) ColorAt(d geom.Tuple) colors.Color {
+	x, y, _ := e.pixelOf(d)
+	return e.canvas.GetPixel(x, y)
+}
+
+func (e *ImageEnvironment) Sample(u, v float64) (geom.Tuple, float64) {
+	t, rowPdf, y := e.rows.sample(v)
+	s, columnPdf, _ := e.columns[y].sample(u)
+
+	theta := t * math.Pi
+	sinTheta := math.Sin(theta)
+	if sinTheta == 0 {
+		return geom.NewVector(0, 1, 0), 0
+	}
+	phi := (0.5 - s) * 2 * math.Pi
+	d := geom.NewVector(sinTheta*math.Sin(phi), math.Cos(theta), sinTheta*math.Cos(phi))
+	// from density over the image to density over the sphere
+	return d, rowPdf * columnPdf / (2 * math.Pi * math.Pi * sinTheta)
+}
+
+func (e *ImageEnvironment) Pdf(d geom.Tuple) float64 {
+	x, y, sinTheta := e.pixelOf(d)
+	if sinTheta == 0 {
+		return 0
+	}
+	return e.rows.pdf(y) * e.columns[y].pdf(x) / (2 * math.Pi * math.Pi * sinTheta)
+}
+
+// pixelOf finds the pixel seen in direction d
+func (e *ImageEnvironment) pixelOf(d geom.Tuple) (x, y int, sinTheta float64) {
+	d = d.Normalize()
+	theta := math.Acos(math.Max(-1, math.Min(1, d.Y)))
+	s := 0.5 - math.Atan2(d.X, d.Z)/(2*math.Pi)
+	if s >= 1 {
+		s -= 1
+	}
+	x = int(s * float64(e.width))
+	y = int(theta / math.Pi * float64(e.height))
+	if x >= e.width {
+		x = e.width - 1
+	}
+	if y >= e.height {
+		y = e.height - 1
+	}
+	return x, y, math.Sin(theta)
+}
+
+// distribution1D picks one of n equal steps over [0, 1) in proportion to its weight
+type distribution1D struct {
+	weights []float64
+	// cdf has n+1 entries from 0 to 1
+	cdf   []float64
+	total float64
+}
+
+func newDistribution1D(weights []float64) distribution1D {
+	n := len(weights)
+	d := distribution1D{weights: weights, cdf: make([]float64, n+1)}
+	for i, w := range weights {
+		d.cdf[i+1] = d.cdf[i] + w
+	}
+	d.total = d.cdf[n]
+	for i := 1; i <= n; i++ {
+		if d.total > 0 {
+			d.cdf[i] /= d.total
+		} else {
+			// all black, fall back to uniform
+			d.cdf[i] = float64(i) / float64(n)
+		}
+	}
+	return d
+}
+
+// sample returns a position in [0, 1), its density and the step it is in
+func (d distribution1D) sample(u float64) (float64, float64, int) {
+	n := len(d.weights)
+	// the last step whose cdf starts at or before u
+	lo, hi := 0, n-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if d.cdf[mid] <= u {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	i := lo
+
+	offset := 0.0
+	if width := d.cdf[i+1] - d.cdf[i]; width > 0 {
+		offset = (u - d.cdf[i]) / width
+	}
+	return (float64(i) + offset) / float64(n), d.pdf(i), i
+}
+
+func (d distribution1D) pdf(i int) float64 {
+	n := len(d.weights)
+	return (d.cdf[i+1] - d.cdf[i]) * float64(n)
+}
+
+// SetEnvironment puts an environment around the world, nil is black
+func (w *World) SetEnvironment(e Environment) {
+	w.environment = e
+}
+
+// SetEnvironmentSamples sets how many directions the whitted integrator gathers environment light from at every hit,
+// each costing up to two shadow rays. 0 is the default and turns environment lighting off, leaving material ambient to stand in for it.
+// while it is on ambient is not added, the environment light replaces it
+func (w *World) SetEnvironmentSamples(n int) {
+	if n < 0 {
+		n = 0
+	}
+	w.environmentSamples = n
+}
+
+// environmentLit reports if the whitted integrator gathers light from the environment
+func (w *World) environmentLit() bool {
+	return w.environment != nil && w.environmentSamples > 0
+}
+
+// background is what a ray sees when it leaves the world in direction d
+func (w *World) background(d geom.Tuple) colors.Color {
+	if w.environment == nil {
+		return colors.Black()
+	}
+	return w.environment.ColorAt(d)
+}
+
+// environmentLight estimates the light from the environment reflected toward the eye.
+// each sample takes one direction from the bright parts of the environment and one from the material,
+// weighted with the balance heuristic so neither a small sun nor a sharp highlight makes fireflies
+func (w *World) environmentLight(c shapes.IntersectionComputed, m materials.Material, samples int, rng *rand.Rand) colors.Color {
+	if w.environment == nil || samples < 1 || !c.Object.GetShaded() {
+		return colors.Black()
+	}
+
+	bsdf := shapes.NewBSDF(m, c.Object, c.OverPoint, c.Eyev, c.Normalv)
+	sum := colors.Black()
+	for i := 0; i < samples; i++ {
+		// toward the environment
+		if d, pdf := w.environment.Sample(uniform(rng), uniform(rng)); pdf > 0 {
+			if f := bsdf.Eval(d); f != (colors.Color{}) && w.reachesEnvironment(c, d) {
+				weight := pdf / (pdf + bsdf.Pdf(d))
+				sum = sum.Add(f.Mul(w.environment.ColorAt(d)).MulBy(d.Dot(c.Normalv) * weight / pdf))
+			}
+		}
+
+		// along the material
+		if d, ok := bsdf.Sample(uniform(rng), uniform(rng), uniform(rng)); ok {
+			pdf := bsdf.Pdf(d)
+			if f := bsdf.Eval(d); pdf > 0 && f != (colors.Color{}) && w.reachesEnvironment(c, d) {
+				weight := pdf / (pdf + w.environment.Pdf(d))
+				sum = sum.Add(f.Mul(w.environment.ColorAt(d)).MulBy(d.Dot(c.Normalv) * weight / pdf))
+			}
+		}
+	}
+	return sum.MulBy(1 / float64(samples))
+}
+
+func (w *World) reachesEnvironment(c shapes.IntersectionComputed, d geom.Tuple) bool {
+	return d.Dot(c.Normalv) > 0 && !w.isOccluded(c.OverPoint, d, math.Inf(1))
+}
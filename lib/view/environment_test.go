@@ -0,0 +1,252 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/patterns"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/robkau/go-raytrace/lib/view/canvas"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func Test_Environment_SeenByMisses(t *testing.T) {
+	w := NewWorld()
+	w.SetEnvironment(NewConstantEnvironment(colors.NewColor(0.2, 0.4, 0.6)))
+	r := geom.RayWith(geom.ZeroPoint(), geom.NewVector(0, 1, 0))
+
+	require.Equal(t, colors.NewColor(0.2, 0.4, 0.6), w.ColorAt(r, 3))
+	require.Equal(t, colors.NewColor(0.2, 0.4, 0.6), NewPathTracer().ColorAt(w, r, 3, rand.New(rand.NewSource(1))))
+	require.Equal(t, colors.Black(), NewWorld().ColorAt(r, 3))
+}
+
+func Test_Environment_SeenInMirror(t *testing.T) {
+	w := NewWorld()
+	w.SetEnvironment(NewPatternEnvironment(patterns.NewPrismaticCube()))
+	mirror := shapes.NewPlane()
+	m := mirror.GetMaterial()
+	m.Color = colors.Black()
+	m.Ambient = 0
+	m.Specular = 0
+	m.Reflective = 1
+	mirror.SetMaterial(m)
+	w.AddObject(mirror)
+
+	r := geom.RayWith(geom.NewPoint(0, 1, 0), geom.NewVector(0, -1, 0))
+
+	// straight up is the main color of the top face
+	require.Equal(t, colors.Brown(), w.ColorAt(r, 1))
+}
+
+func Test_PatternEnvironment_CubeFaces(t *testing.T) {
+	e := NewPatternEnvironment(patterns.NewPrismaticCube())
+
+	require.Equal(t, colors.Brown(), e.ColorAt(geom.NewVector(0, 1, 0)))
+	require.Equal(t, colors.Purple(), e.ColorAt(geom.NewVector(0, -5, 0)))
+	require.Equal(t, colors.Red(), e.ColorAt(geom.NewVector(1, 0.1, 0.1)))
+}
+
+func Test_Distribution1D(t *testing.T) {
+	d := newDistribution1D([]float64{0, 1, 3, 0})
+
+	type args struct {
+		u      float64
+		expect int
+	}
+
+	tests := []args{
+		{0, 1},
+		{0.2, 1},
+		{0.25, 2},
+		{0.999, 2},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			x, pdf, i := d.sample(tt.u)
+			require.Equal(t, tt.expect, i)
+			require.Equal(t, d.pdf(i), pdf)
+			require.True(t, x >= float64(i)/4 && x < float64(i+1)/4)
+		})
+	}
+
+	require.Equal(t, 0.0, d.pdf(0))
+	require.Equal(t, 1.0, d.pdf(1))
+	require.Equal(t, 3.0, d.pdf(2))
+
+	uniform := newDistribution1D([]float64{0, 0})
+	require.Equal(t, 1.0, uniform.pdf(0))
+	_, _, i := uniform.sample(0.75)
+	require.Equal(t, 1, i)
+}
+
+// equirectangular image, white above the horizon and black below
+func skyImage(w, h int) *canvas.Canvas {
+	c := canvas.NewCanvas(w, h)
+	for y := 0; y < h/2; y++ {
+		for x := 0; x < w; x++ {
+			c.SetPixel(x, y, colors.White())
+		}
+	}
+	return c
+}
+
+func Test_ImageEnvironment_MatchesSphericalMap(t *testing.T) {
+	c := canvas.NewCanvas(4, 2)
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			c.SetPixel(x, y, colors.NewColor(float64(x), float64(y), 0))
+		}
+	}
+	e := NewImageEnvironment(c)
+	p := patterns.NewTextureMapPattern(patterns.NewUVImage(c), patterns.SphericalMap)
+
+	for _, d := range []geom.Tuple{
+		geom.NewVector(0, 0.5, 1),
+		geom.NewVector(1, -0.5, 0),
+		geom.NewVector(-1, 0.5, 0.2),
+		geom.NewVector(0.2, -0.5, -1),
+	} {
+		require.Equal(t, p.ColorAt(geom.NewPoint(d.X, d.Y, d.Z).Normalize()), e.ColorAt(d))
+	}
+}
+
+func Test_ImageEnvironment_Sample(t *testing.T) {
+	e := NewImageEnvironment(skyImage(16, 8))
+	rng := rand.New(rand.NewSource(1))
+
+	// samples only go where there is light, with the density Pdf reports
+	for i := 0; i < 1000; i++ {
+		d, pdf := e.Sample(rng.Float64(), rng.Float64())
+		require.InDelta(t, 1, d.Mag(), 1e-9)
+		require.True(t, d.Y >= 0)
+		require.Equal(t, colors.White(), e.ColorAt(d))
+		require.InDelta(t, pdf, e.Pdf(d), 1e-6*pdf)
+	}
+
+	// the density integrates to one over the sphere
+	sum := 0.0
+	n := 20000
+	for i := 0; i < n; i++ {
+		sum += e.Pdf(geom.SampleSphere(rng.Float64(), rng.Float64())) * 4 * math.Pi
+	}
+	require.InDelta(t, 1, sum/float64(n), 0.03)
+}
+
+func floorUnderSky(t *testing.T, e Environment) (*World, shapes.IntersectionComputed) {
+	w := NewWorld()
+	w.SetEnvironment(e)
+	floor := shapes.NewPlane()
+	m := floor.GetMaterial()
+	m.Specular = 0
+	floor.SetMaterial(m)
+	w.AddObject(floor)
+
+	r := geom.RayWith(geom.NewPoint(0, 1, 0), geom.NewVector(0, -1, 0))
+	xs := w.Intersect(r)
+	i, ok := xs.Hit()
+	require.True(t, ok)
+	return w, i.Compute(r, xs)
+}
+
+func Test_EnvironmentLight_Diffuse(t *testing.T) {
+	type args struct {
+		e Environment
+	}
+
+	// a diffuse floor under a sky of radiance 1 reflects its albedo
+	tests := []args{
+		{NewConstantEnvironment(colors.White())},
+		{NewPatternEnvironment(patterns.NewSolidColorPattern(colors.White()))},
+		{NewImageEnvironment(skyImage(32, 16))},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			w, c := floorUnderSky(t, tt.e)
+			col := w.environmentLight(c, c.Object.GetMaterial(), 20000, rand.New(rand.NewSource(1)))
+			require.InDelta(t, 0.9, col.R, 0.02)
+		})
+	}
+}
+
+func Test_EnvironmentLight_Occluded(t *testing.T) {
+	w, c := floorUnderSky(t, NewConstantEnvironment(colors.White()))
+	roof := shapes.NewPlane()
+	roof.SetTransform(geom.Translate(0, 2, 0))
+	w.AddObject(roof)
+
+	col := w.environmentLight(c, c.Object.GetMaterial(), 1000, rand.New(rand.NewSource(1)))
+
+	require.Equal(t, colors.Black(), col)
+}
+
+func Test_EnvironmentLight_Glossy(t *testing.T) {
+	// a glossy floor reflects a bright spot of sky in the mirror direction and not elsewhere
+	c := canvas.NewCanvas(64, 32)
+	c.SetPixel(32, 8, colors.White().MulBy(1000))
+	e := NewImageEnvironment(c)
+	sun, _ := e.Sample(0.5, 0.5)
+
+	w := NewWorld()
+	w.SetEnvironment(e)
+	floor := shapes.NewPlane()
+	m := floor.GetMaterial()
+	m.Diffuse = 0
+	m.Specular = 1
+	m.Shininess = 50
+	floor.SetMaterial(m)
+	w.AddObject(floor)
+
+	shade := func(eye geom.Tuple) colors.Color {
+		r := geom.RayWith(eye, geom.ZeroPoint().Sub(eye).Normalize())
+		xs := w.Intersect(r)
+		i, ok := xs.Hit()
+		require.True(t, ok)
+		return w.environmentLight(i.Compute(r, xs), m, 200, rand.New(rand.NewSource(1)))
+	}
+
+	mirrored := shade(geom.NewPoint(-sun.X, sun.Y, -sun.Z))
+	elsewhere := shade(geom.NewPoint(sun.X, sun.Y, sun.Z))
+
+	require.True(t, mirrored.R > 1)
+	require.True(t, elsewhere.R < mirrored.R/100)
+}
+
+func Test_EnvironmentSamples_OffByDefault(t *testing.T) {
+	w, c := floorUnderSky(t, NewConstantEnvironment(colors.White()))
+	w.AddLight(shapes.NewPointLight(geom.NewPoint(0, 10, 0), colors.White()))
+
+	col := w.shadeHit(c, 0, w.seededTrace())
+	require.InDelta(t, 0.1+0.9, col.R, 1e-9)
+}
+
+func Test_EnvironmentSamples(t *testing.T) {
+	type args struct {
+		samples  int
+		expected float64
+	}
+
+	// the floor has ambient 0.1 and diffuse 0.9, a light straight above and a white sky
+	tests := []args{
+		// ambient stands in for the sky
+		{samples: 0, expected: 0.1 + 0.9},
+		// the sky replaces ambient
+		{samples: 4000, expected: 0.9 + 0.9},
+		{samples: -1, expected: 0.1 + 0.9},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			w, c := floorUnderSky(t, NewConstantEnvironment(colors.White()))
+			w.AddLight(shapes.NewPointLight(geom.NewPoint(0, 10, 0), colors.White()))
+			w.SetEnvironmentSamples(tt.samples)
+
+			col := w.shadeHit(c, 0, w.seededTrace())
+			require.InDelta(t, tt.expected, col.R, 0.03)
+		})
+	}
+}
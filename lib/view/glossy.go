@@ -0,0 +1,74 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"math/rand"
+)
+
+// DefaultGlossySamples is how many rays the whitted integrator spreads over a rough mirror or glass surface
+const DefaultGlossySamples = 8
+
+// SetGlossySamples sets how many reflection and refraction rays leave the first rough surface along a whitted ray.
+// rough surfaces seen in those rays trace one ray each, so the cost grows with the samples but not with the bounces
+func (w *World) SetGlossySamples(n int) {
+	if n < 1 {
+		n = 1
+	}
+	w.glossySamples = n
+}
+
+// glossyColor averages the color along the glossy samples rays from next, skipping rays that went under the surface
+func (w *World) glossyColor(remaining int, tr trace, next func() (geom.Ray, bool)) colors.Color {
+	sum := colors.Black()
+	traced := 0
+	single := tr
+	single.glossySamples = 1
+	for i := 0; i < tr.glossySamples; i++ {
+		r, ok := next()
+		if !ok {
+			continue
+		}
+		sum = sum.Add(w.colorAt(r, remaining-1, single))
+		traced++
+	}
+	if traced == 0 {
+		return sum
+	}
+	return sum.MulBy(1 / float64(traced))
+}
+
+// microfacetNormal tilts the surface normal like a random bump on a surface with the material roughness
+func microfacetNormal(c shapes.IntersectionComputed, rng *rand.Rand) geom.Tuple {
+	alpha := shapes.GGXAlpha(c.Object.GetMaterial().Roughness)
+	return shapes.SampleGGXNormal(c.Normalv, alpha, uniform(rng), uniform(rng))
+}
+
+// reflectDirection is the mirror direction, spread around Reflectv on rough materials.
+// false when the spread direction points into the surface
+func reflectDirection(c shapes.IntersectionComputed, rng *rand.Rand) (geom.Tuple, bool) {
+	if c.Object.GetMaterial().Roughness <= 0 {
+		return c.Reflectv, true
+	}
+	h := microfacetNormal(c, rng)
+	if c.Eyev.Dot(h) <= 0 {
+		return geom.Tuple{}, false
+	}
+	d := c.Eyev.Neg().Reflect(h)
+	return d, d.Dot(c.Normalv) > 0
+}
+
+// roughRefractDirection is the refracted direction, spread around the one from refractDirection on rough materials.
+// false on total internal refraction or when the spread direction comes back out of the surface
+func roughRefractDirection(c shapes.IntersectionComputed, nRatio float64, rng *rand.Rand) (geom.Tuple, bool) {
+	if c.Object.GetMaterial().Roughness <= 0 {
+		return refractDirection(c, nRatio)
+	}
+	h := microfacetNormal(c, rng)
+	if c.Eyev.Dot(h) <= 0 {
+		return geom.Tuple{}, false
+	}
+	d, ok := refract(c.Eyev, h, nRatio)
+	return d, ok && d.Dot(c.Normalv) < 0
+}
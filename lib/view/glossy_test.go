@@ -0,0 +1,168 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/patterns"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// roughMirrorWorld is a black mirror floor under an environment that is white toward +x and black toward -x
+func roughMirrorWorld(roughness float64) (*World, geom.Ray) {
+	w := NewWorld()
+	w.SetEnvironment(NewPatternEnvironment(patterns.NewStripePattern(patterns.NewSolidColorPattern(colors.White()), patterns.NewSolidColorPattern(colors.Black()))))
+	mirror := shapes.NewPlane()
+	m := mirror.GetMaterial()
+	m.Color = colors.Black()
+	m.Ambient = 0
+	m.Specular = 0
+	m.Reflective = 1
+	m.Roughness = roughness
+	mirror.SetMaterial(m)
+	w.AddObject(mirror)
+
+	// looking straight down, a perfect mirror sees the edge between white and black
+	return w, geom.RayWith(geom.NewPoint(0, 1, 0), geom.NewVector(0, -1, 0))
+}
+
+func Test_SetGlossySamples(t *testing.T) {
+	w := NewWorld()
+	require.Equal(t, DefaultGlossySamples, w.glossySamples)
+
+	w.SetGlossySamples(32)
+	require.Equal(t, 32, w.glossySamples)
+
+	// at least one ray is always traced
+	w.SetGlossySamples(0)
+	require.Equal(t, 1, w.glossySamples)
+}
+
+func Test_GlossyReflection_SingleSampleSeesOneSide(t *testing.T) {
+	w, r := roughMirrorWorld(0.3)
+	w.SetGlossySamples(1)
+
+	rng := rand.New(rand.NewSource(1))
+	seen := map[colors.Color]int{}
+	for i := 0; i < 100; i++ {
+		seen[WhittedIntegrator{}.ColorAt(w, r, 2, rng)]++
+	}
+
+	require.Len(t, seen, 2)
+	require.Greater(t, seen[colors.White()], 0)
+	require.Greater(t, seen[colors.Black()], 0)
+}
+
+func Test_GlossyReflection_AveragesSamples(t *testing.T) {
+	w, r := roughMirrorWorld(0.3)
+	w.SetGlossySamples(512)
+
+	c := w.ColorAt(r, 2)
+
+	// blurred over the edge, half white and half black
+	require.InDelta(t, 0.5, c.R, 0.15)
+	require.Equal(t, c.R, c.G)
+	require.Equal(t, c.R, c.B)
+}
+
+func Test_GlossyReflection_NoReflectionsRemaining(t *testing.T) {
+	w, r := roughMirrorWorld(0.3)
+
+	require.Equal(t, colors.Black(), w.ColorAt(r, 0))
+}
+
+func Test_GlossyReflection_PathTracer(t *testing.T) {
+	w, r := roughMirrorWorld(0.3)
+	p := NewPathTracer()
+	rng := rand.New(rand.NewSource(1))
+
+	sum := 0.0
+	n := 2000
+	for i := 0; i < n; i++ {
+		c := p.ColorAt(w, r, 2, rng)
+		// one ray per bounce lands on one side
+		require.Contains(t, []colors.Color{colors.White(), colors.Black()}, c)
+		sum += c.R
+	}
+	require.InDelta(t, 0.5, sum/float64(n), 0.05)
+}
+
+func Test_ReflectDirection_SpreadsWithRoughness(t *testing.T) {
+	type args struct {
+		roughness float64
+		// bounds of the mean cosine to the mirror direction
+		minCos float64
+		maxCos float64
+	}
+
+	tests := []args{
+		{roughness: 0, minCos: 1, maxCos: 1},
+		{roughness: 0.1, minCos: 0.99, maxCos: 1},
+		{roughness: 0.5, minCos: 0.8, maxCos: 0.98},
+		{roughness: 1, minCos: 0.3, maxCos: 0.8},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			p := shapes.NewPlane()
+			m := p.GetMaterial()
+			m.Reflective = 1
+			m.Roughness = tt.roughness
+			p.SetMaterial(m)
+			r := geom.RayWith(geom.NewPoint(0, 1, -1), geom.NewVector(0, -1, 1).Normalize())
+			i := shapes.NewIntersection(math.Sqrt(2), p)
+			c := i.Compute(r, shapes.NewIntersections(i))
+			rng := rand.New(rand.NewSource(1))
+
+			sum := 0.0
+			n := 0
+			for s := 0; s < 1000; s++ {
+				d, ok := reflectDirection(c, rng)
+				if !ok {
+					continue
+				}
+				require.Greater(t, d.Dot(c.Normalv), 0.0)
+				require.InDelta(t, 1, d.Mag(), 1e-9)
+				sum += d.Dot(c.Reflectv)
+				n++
+			}
+			require.Greater(t, n, 0)
+			require.GreaterOrEqual(t, sum/float64(n), tt.minCos-1e-9)
+			require.LessOrEqual(t, sum/float64(n), tt.maxCos+1e-9)
+		})
+	}
+}
+
+func Test_RoughRefractDirection_FrostedGlass(t *testing.T) {
+	p := shapes.NewPlane()
+	m := materials.NewGlassMaterial()
+	m.Roughness = 0.4
+	p.SetMaterial(m)
+	r := geom.RayWith(geom.NewPoint(0, 1, -1), geom.NewVector(0, -1, 1).Normalize())
+	i := shapes.NewIntersection(math.Sqrt(2), p)
+	c := i.Compute(r, shapes.NewIntersections(i))
+	smooth, ok := refractDirection(c, c.N1/c.N2)
+	require.True(t, ok)
+	rng := rand.New(rand.NewSource(1))
+
+	sum := 0.0
+	n := 0
+	for s := 0; s < 1000; s++ {
+		d, ok := roughRefractDirection(c, c.N1/c.N2, rng)
+		if !ok {
+			continue
+		}
+		// through the surface, spread around the smooth refraction
+		require.Less(t, d.Dot(c.Normalv), 0.0)
+		sum += d.Normalize().Dot(smooth.Normalize())
+		n++
+	}
+	require.Greater(t, n, 900)
+	require.Greater(t, sum/float64(n), 0.8)
+	require.Less(t, sum/float64(n), 0.999)
+}
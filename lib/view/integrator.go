@@ -0,0 +1,224 @@
+package view
+
+import (
+	"fmt"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"math"
+	"math/rand"
+)
+
+// Integrator finds the color seen along a camera ray
+type Integrator interface {
+	Name() string
+	// ColorAt follows the ray through the world for at most remaining bounces.
+	// rng belongs to the calling render worker
+	ColorAt(w *World, r geom.Ray, remaining int, rng *rand.Rand) colors.Color
+}
+
+// WhittedIntegrator is Phong direct lighting with mirror reflection and refraction.
+// soft lighting from emissive shapes and the environment, rough surfaces and media draw from the rng of the render worker
+type WhittedIntegrator struct{}
+
+func (WhittedIntegrator) Name() string {
+	return "whitted"
+}
+
+func (WhittedIntegrator) ColorAt(w *World, r geom.Ray, remaining int, rng *rand.Rand) colors.Color {
+	return w.colorAt(r, remaining, w.newTrace(rng))
+}
+
+// PathTracer is a unidirectional path tracer with global illumination.
+// every bounce adds direct light from the lights and emissive shapes, then continues in one randomly chosen direction:
+// cosine weighted diffuse, mirror reflection or refraction, spread by the material roughness.
+// dispersive glass sends each path on with one wavelength band, and media add light scattered once from the lights. material ambient is replaced by the indirect light,
+// and unshaded objects glow with their own color.
+// emissive shapes and the environment are sampled for direct light, so they only count when seen directly or through mirrors and glass.
+// a single sample is noisy, combine it with supersampling or accumulation
+type PathTracer struct {
+	// RouletteDepth is the bounce after which paths are ended at random, weighted by how much they still carry
+	RouletteDepth int
+}
+
+func NewPathTracer() PathTracer {
+	return PathTracer{RouletteDepth: 3}
+}
+
+func (PathTracer) Name() string {
+	return "path"
+}
+
+func (p PathTracer) ColorAt(w *World, r geom.Ray, remaining int, rng *rand.Rand) colors.Color {
+	col := colors.Black()
+	throughput := colors.White()
+	specular := true
+	// 0 until a dispersive surface picks one band of the spectrum for the rest of the path
+	wavelength := 0.0
+
+	for depth := 0; ; depth++ {
+		c, ok := w.hit(r)
+		if !ok {
+			col = col.Add(throughput.Mul(w.inScattered(w.fog, r, math.Inf(1), rng)))
+			if specular {
+				// after diffuse bounces the environment was already sampled as direct light
+				col = col.Add(throughput.Mul(w.background(r.Direction)).MulBy(materials.MediumTransmittance(w.fog, math.Inf(1))))
+			}
+			return col
+		}
+		medium := w.mediumIn(c.Medium)
+		col = col.Add(throughput.Mul(w.inScattered(medium, r, c.Distance, rng)))
+		throughput = throughput.Mul(transmittance(c)).MulBy(materials.MediumTransmittance(medium, c.Distance))
+
+		m := c.Object.GetMaterial()
+		surface := shapes.SurfaceColor(m, c.Object, c.OverPoint)
+		if !c.Object.GetShaded() {
+			return col.Add(throughput.Mul(surface))
+		}
+
+		if specular || !w.isSampledEmitter(c.Object) {
+			col = col.Add(throughput.Mul(m.Emission))
+		}
+
+		// next event estimation, ambient is left to the indirect bounces
+		direct := m
+		direct.Ambient = 0
+		col = col.Add(throughput.Mul(w.directLight(c, direct).
+			Add(w.emittedLight(c, direct, 1, rng)).
+			Add(w.environmentLight(c, direct, 1, rng))))
+
+		if depth >= remaining {
+			return col
+		}
+
+		if wavelength == 0 && m.Transparency > 0 && isDispersive(c) {
+			b := spectralBands[int(rng.Float64()*float64(len(spectralBands)))%len(spectralBands)]
+			wavelength = b.wavelength
+			// picking one of n bands weighs it by n
+			throughput = throughput.Mul(b.weight).MulBy(float64(len(spectralBands)))
+		}
+
+		next, weight, diffuse, ok := p.scatter(c, surface, wavelength, rng)
+		if !ok {
+			return col
+		}
+		specular = !diffuse
+		throughput = throughput.Mul(weight)
+		r = next
+
+		if depth >= p.RouletteDepth {
+			survive := math.Min(0.95, math.Max(throughput.R, math.Max(throughput.G, throughput.B)))
+			if rng.Float64() >= survive {
+				return col
+			}
+			throughput = throughput.MulBy(1 / survive)
+		}
+	}
+}
+
+// scatter picks the next ray between diffuse, reflection and refraction in proportion to how much each carries.
+// weight is the color carried divided by the chance of the choice
+func (p PathTracer) scatter(c shapes.IntersectionComputed, surface colors.Color, wavelength float64, rng *rand.Rand) (next geom.Ray, weight colors.Color, diffuse bool, ok bool) {
+	m := c.Object.GetMaterial()
+	if m.Model == materials.ModelMetallicRoughness {
+		return p.scatterMicrofacet(c, m, wavelength, rng)
+	}
+	albedo := surface.MulBy(m.Diffuse)
+	reflective := m.Reflective
+	transparency := m.Transparency
+	if reflective > 0 && transparency > 0 {
+		// fresnel splits the light between both like in the whitted integrator
+		reflectance := c.Schlick()
+		reflective *= reflectance
+		transparency *= 1 - reflectance
+	}
+
+	pDiffuse := math.Max(0, (albedo.R+albedo.G+albedo.B)/3)
+	total := pDiffuse + reflective + transparency
+	if total <= 0 {
+		return geom.Ray{}, colors.Color{}, false, false
+	}
+
+	choice := rng.Float64() * total
+	switch {
+	case choice < pDiffuse:
+		t, b := geom.OrthonormalBasis(c.Normalv)
+		direction := geom.ToBasis(geom.SampleCosineHemisphere(rng.Float64(), rng.Float64()), t, b, c.Normalv)
+		// cosine weighting cancels the lambert term, leaving the albedo
+		return geom.RayWith(c.OverPoint, direction), albedo.MulBy(total / pDiffuse), true, true
+	case choice < pDiffuse+reflective:
+		direction, ok := reflectDirection(c, rng)
+		if !ok {
+			return geom.Ray{}, colors.Color{}, false, false
+		}
+		return geom.RayWith(c.OverPoint, direction), colors.White().MulBy(total), false, true
+	default:
+		direction, ok := roughRefractDirection(c, refractiveRatio(c, wavelength), rng)
+		if !ok {
+			return geom.Ray{}, colors.Color{}, false, false
+		}
+		return geom.RayWith(c.UnderPoint, direction), colors.White().MulBy(total), false, true
+	}
+}
+
+// scatterMicrofacet samples the metallic roughness lobes, still leaving Reflective and Transparency to mirror and glass
+func (p PathTracer) scatterMicrofacet(c shapes.IntersectionComputed, m materials.Material, wavelength float64, rng *rand.Rand) (next geom.Ray, weight colors.Color, diffuse bool, ok bool) {
+	reflective := m.Reflective
+	transparency := m.Transparency
+	if reflective > 0 && transparency > 0 {
+		reflectance := c.Schlick()
+		reflective *= reflectance
+		transparency *= 1 - reflectance
+	}
+	// the surface keeps what mirror and glass leave over
+	pSurface := math.Max(0, 1-reflective-transparency)
+	total := pSurface + reflective + transparency
+	if total <= 0 {
+		return geom.Ray{}, colors.Color{}, false, false
+	}
+
+	choice := rng.Float64() * total
+	switch {
+	case choice < pSurface:
+		bsdf := shapes.NewBSDF(m, c.Object, c.OverPoint, c.Eyev, c.Normalv)
+		direction, ok := bsdf.Sample(rng.Float64(), rng.Float64(), rng.Float64())
+		if !ok {
+			return geom.Ray{}, colors.Color{}, false, false
+		}
+		pdf := bsdf.Pdf(direction)
+		if pdf <= 0 {
+			return geom.Ray{}, colors.Color{}, false, false
+		}
+		// counted like a diffuse bounce, direct light was gathered with the same bsdf
+		f := bsdf.Eval(direction).MulBy(direction.Dot(c.Normalv) / pdf)
+		return geom.RayWith(c.OverPoint, direction), f.MulBy(total / pSurface), true, true
+	case choice < pSurface+reflective:
+		direction, ok := reflectDirection(c, rng)
+		if !ok {
+			return geom.Ray{}, colors.Color{}, false, false
+		}
+		return geom.RayWith(c.OverPoint, direction), colors.White().MulBy(total), false, true
+	default:
+		direction, ok := roughRefractDirection(c, refractiveRatio(c, wavelength), rng)
+		if !ok {
+			return geom.Ray{}, colors.Color{}, false, false
+		}
+		return geom.RayWith(c.UnderPoint, direction), colors.White().MulBy(total), false, true
+	}
+}
+
+// Integrators are the named integrators with their default settings
+var Integrators = []Integrator{
+	WhittedIntegrator{},
+	NewPathTracer(),
+}
+
+func ParseIntegrator(name string) (Integrator, error) {
+	for _, i := range Integrators {
+		if i.Name() == name {
+			return i, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown integrator %q", name)
+}
@@ -0,0 +1,198 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func Test_WhittedIntegrator_MatchesWorld(t *testing.T) {
+	w := defaultWorld()
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	require.Equal(t, w.ColorAt(r, 3), WhittedIntegrator{}.ColorAt(w, r, 3, nil))
+}
+
+func Test_WhittedIntegrator_UsesWorkerRng(t *testing.T) {
+	w, _ := floorUnderEmitter(t, false)
+	r := geom.RayWith(geom.NewPoint(0, 0.5, -0.5), geom.NewVector(0, -math.Sqrt2/2, math.Sqrt2/2))
+
+	// soft light from the emitter is sampled, the same seed gives the same color
+	first := WhittedIntegrator{}.ColorAt(w, r, 3, rand.New(rand.NewSource(7)))
+	require.Equal(t, first, WhittedIntegrator{}.ColorAt(w, r, 3, rand.New(rand.NewSource(7))))
+	require.NotEqual(t, first, WhittedIntegrator{}.ColorAt(w, r, 3, rand.New(rand.NewSource(8))))
+	require.Equal(t, w.ColorAt(r, 3), w.ColorAt(r, 3))
+}
+
+func Test_PathTracer_Miss(t *testing.T) {
+	w := defaultWorld()
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 1, 0))
+
+	require.Equal(t, colors.Black(), NewPathTracer().ColorAt(w, r, 3, rand.New(rand.NewSource(1))))
+}
+
+func Test_PathTracer_DirectLightWithoutAmbient(t *testing.T) {
+	w := defaultWorld()
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	col := NewPathTracer().ColorAt(w, r, 0, rand.New(rand.NewSource(1)))
+
+	// the whitted color without the 0.1 ambient of the outer sphere
+	require.Equal(t, colors.NewColor(0.30066, 0.37583, 0.2255), col.RoundTo(5))
+}
+
+func Test_PathTracer_UnshadedIsEmitter(t *testing.T) {
+	w := NewWorld()
+	s := shapes.NewSphere()
+	m := materials.NewMaterial()
+	m.Color = colors.NewColor(0.2, 0.4, 0.6)
+	s.SetMaterial(m)
+	s.SetShaded(false)
+	w.AddObject(s)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+
+	require.Equal(t, colors.NewColor(0.2, 0.4, 0.6), NewPathTracer().ColorAt(w, r, 3, rand.New(rand.NewSource(1))))
+}
+
+// a diffuse sphere inside a glowing white sphere reflects its albedo of the glow
+func Test_PathTracer_DiffuseBounce(t *testing.T) {
+	w := NewWorld()
+	sky := shapes.NewSphere()
+	sky.SetTransform(geom.Scale(20, 20, 20))
+	sky.SetShaded(false)
+	w.AddObject(sky)
+	s := shapes.NewSphere()
+	m := materials.NewMaterial()
+	m.Diffuse = 0.5
+	s.SetMaterial(m)
+	w.AddObject(s)
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 100; i++ {
+		col := NewPathTracer().ColorAt(w, r, 3, rng)
+		require.InDelta(t, 0.5, col.R, 1e-9)
+	}
+}
+
+func Test_PathTracer_Mirror(t *testing.T) {
+	w := NewWorld()
+	floor := shapes.NewPlane()
+	m := materials.NewMaterial()
+	m.Ambient = 0
+	m.Diffuse = 0
+	m.Specular = 0
+	m.Reflective = 1
+	floor.SetMaterial(m)
+	w.AddObject(floor)
+	light := shapes.NewSphere()
+	light.SetTransform(geom.Translate(0, 3, 3))
+	ml := materials.NewMaterial()
+	ml.Color = colors.NewColor(1, 0, 0)
+	light.SetMaterial(ml)
+	light.SetShaded(false)
+	w.AddObject(light)
+	r := geom.RayWith(geom.NewPoint(0, 3, -3), geom.NewVector(0, -math.Sqrt2/2, math.Sqrt2/2))
+
+	require.Equal(t, colors.NewColor(1, 0, 0), NewPathTracer().ColorAt(w, r, 1, rand.New(rand.NewSource(1))))
+	require.Equal(t, colors.Black(), NewPathTracer().ColorAt(w, r, 0, rand.New(rand.NewSource(1))))
+}
+
+func Test_PathTracer_GlassConverges(t *testing.T) {
+	// fresnel weighted reflection and refraction average out to the whitted result
+	w := NewWorld()
+	// the light makes the whitted sky as bright as its color, like the path traced emitter
+	w.AddPointLight(shapes.NewPointLight(geom.NewPoint(0, 10, 0), colors.White()))
+	sky := shapes.NewSphere()
+	sky.SetTransform(geom.Scale(20, 20, 20))
+	ms := materials.NewMaterial()
+	ms.Ambient = 1
+	ms.Diffuse = 0
+	ms.Specular = 0
+	sky.SetMaterial(ms)
+	sky.SetShaded(false)
+	w.AddObject(sky)
+	glass := shapes.NewSphere()
+	m := materials.NewGlassMaterial()
+	m.Diffuse = 0
+	m.Specular = 0
+	m.Ambient = 0
+	glass.SetMaterial(m)
+	w.AddObject(glass)
+	r := geom.RayWith(geom.NewPoint(0, 0.5, -5), geom.NewVector(0, 0, 1))
+	rng := rand.New(rand.NewSource(1))
+
+	sum := colors.Black()
+	n := 4000
+	for i := 0; i < n; i++ {
+		sum = sum.Add(PathTracer{RouletteDepth: 10}.ColorAt(w, r, 5, rng))
+	}
+
+	require.InDelta(t, w.ColorAt(r, 5).R, sum.R/float64(n), 0.03)
+}
+
+func Test_ParseIntegrator(t *testing.T) {
+	for _, i := range Integrators {
+		parsed, err := ParseIntegrator(i.Name())
+		require.NoError(t, err)
+		require.Equal(t, i, parsed)
+	}
+	_, err := ParseIntegrator("bidirectional")
+	require.Error(t, err)
+}
+
+func Test_Camera_WithIntegrator(t *testing.T) {
+	w := defaultWorld()
+	c := NewCameraAt(11, 11, math.Pi/2, geom.NewPoint(0, 0, -5), geom.ZeroPoint()).WithIntegrator(NewPathTracer())
+
+	image := c.Render(w, 0, 1)
+
+	require.Equal(t, colors.NewColor(0.30066, 0.37583, 0.2255), image.GetPixel(5, 5).RoundTo(5))
+}
+
+func Test_PathTracer_MicrofacetScatter(t *testing.T) {
+	w := NewWorld()
+	w.SetEnvironment(NewConstantEnvironment(colors.White()))
+	floor := shapes.NewPlane()
+	floor.SetMaterial(materials.NewMetallicRoughnessMaterial(colors.NewColor(1, 0.8, 0.5), 1, 0.4))
+	w.AddObject(floor)
+	r := geom.RayWith(geom.NewPoint(0, 1, -1), geom.NewVector(0, -1, 1).Normalize())
+	c, ok := w.hit(r)
+	require.True(t, ok)
+	rng := rand.New(rand.NewSource(1))
+
+	// bounces carry the reflected fraction of the light, the same the environment lighting gathers from a white sky
+	p := NewPathTracer()
+	sum := colors.Black()
+	n := 50000
+	for i := 0; i < n; i++ {
+		next, weight, diffuse, ok := p.scatter(c, colors.White(), 0, rng)
+		if !ok {
+			continue
+		}
+		require.True(t, diffuse)
+		require.True(t, next.Direction.Y > 0)
+		sum = sum.Add(weight)
+	}
+	scattered := sum.MulBy(1 / float64(n))
+	gathered := w.environmentLight(c, floor.GetMaterial(), n, rng)
+
+	require.InDelta(t, gathered.R, scattered.R, 0.03)
+	require.InDelta(t, gathered.B, scattered.B, 0.03)
+	require.True(t, scattered.R > scattered.B)
+}
+
+func Test_EmittedLight_Microfacet(t *testing.T) {
+	w, c := floorUnderEmitter(t, false)
+	m := materials.NewMetallicRoughnessMaterial(colors.White(), 0, 1)
+
+	col := w.emittedLight(c, m, 20000, rand.New(rand.NewSource(1)))
+
+	// close to the lambertian floor with albedo 1, less what fresnel reflects
+	require.InDelta(t, 1.0/25, col.R, 0.004)
+}
@@ -0,0 +1,40 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/geom"
+	"math/rand"
+)
+
+// Lens turns the pinhole camera into a thin lens camera with depth of field.
+// the zero value is a pinhole
+type Lens struct {
+	// Aperture is the diameter of the lens in world units
+	Aperture float64
+	// FocalDistance is the distance from the camera to the plane in focus.
+	// zero focuses on the point the camera is looking at
+	FocalDistance float64
+	// Blades shapes the aperture as a polygon for bokeh, fewer than 3 is a round aperture
+	Blades int
+	// Rotation turns the polygon aperture, in radians
+	Rotation float64
+}
+
+func (l Lens) isPinhole() bool {
+	return l.Aperture <= 0
+}
+
+// sample picks a point on the lens in camera space. a nil rng picks the lens center
+func (l Lens) sample(rng *rand.Rand) (float64, float64) {
+	if l.isPinhole() || rng == nil {
+		return 0, 0
+	}
+
+	var x, y float64
+	if l.Blades >= 3 {
+		x, y = geom.SamplePolygon(l.Blades, l.Rotation, rng.Float64(), rng.Float64(), rng.Float64())
+	} else {
+		x, y = geom.SampleDisk(rng.Float64(), rng.Float64())
+	}
+	radius := l.Aperture / 2
+	return x * radius, y * radius
+}
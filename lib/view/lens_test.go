@@ -0,0 +1,70 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func Test_Lens_PinholeUnchanged(t *testing.T) {
+	c := NewCamera(201, 101, math.Pi/2)
+	rng := rand.New(rand.NewSource(1))
+
+	require.Equal(t, c.rayForPixel(10, 20), c.rayForPixelOffset(10, 20, 0.5, 0.5, rng))
+}
+
+func Test_Lens_RaysMeetOnFocalPlane(t *testing.T) {
+	type args struct {
+		l      Lens
+		expect geom.Tuple
+	}
+
+	tests := []args{
+		// focus on the looked at point by default
+		{Lens{Aperture: 1}, geom.NewPoint(0, 0, 0)},
+		{Lens{Aperture: 1, FocalDistance: 2}, geom.NewPoint(0, 0, -3)},
+		{Lens{Aperture: 0.5, FocalDistance: 8, Blades: 6, Rotation: 0.3}, geom.NewPoint(0, 0, 3)},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			c := NewCameraAt(201, 101, math.Pi/2, geom.NewPoint(0, 0, -5), geom.ZeroPoint()).WithLens(tt.l)
+			rng := rand.New(rand.NewSource(1))
+
+			for i := 0; i < 20; i++ {
+				r := c.rayForPixelOffset(100, 50, 0.5, 0.5, rng)
+
+				// origin is on the lens
+				require.InDelta(t, -5, r.Origin.Z, 1e-9)
+				require.LessOrEqual(t, math.Hypot(r.Origin.X, r.Origin.Y), tt.l.Aperture/2+1e-9)
+
+				// and the ray passes through the center of the focal plane
+				p := r.Position((tt.expect.Z - r.Origin.Z) / r.Direction.Z)
+				require.Equal(t, tt.expect.RoundTo(5), p.RoundTo(5))
+			}
+		})
+	}
+}
+
+func Test_Lens_NilRngUsesCenter(t *testing.T) {
+	c := NewCameraAt(201, 101, math.Pi/2, geom.NewPoint(0, 0, -5), geom.ZeroPoint())
+
+	require.Equal(t, c.rayForPixel(3, 4).Direction.RoundTo(9), c.WithLens(Lens{Aperture: 2}).rayForPixel(3, 4).Direction.RoundTo(9))
+}
+
+func Test_Lens_WithProjection(t *testing.T) {
+	c := NewCameraAt(11, 11, math.Pi/2, geom.NewPoint(0, 0, -5), geom.ZeroPoint()).
+		WithProjection(Orthographic{HalfWidth: 1}).
+		WithLens(Lens{Aperture: 1})
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		r := c.rayForPixelOffset(5, 5, 0.5, 0.5, rng)
+
+		p := r.Position(-r.Origin.Z / r.Direction.Z)
+		require.Equal(t, geom.ZeroPoint(), p.RoundTo(9))
+	}
+}
@@ -0,0 +1,77 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"math"
+	"math/rand"
+)
+
+// mediumSteps is how many points along a ray through a medium gather light from the lights
+const mediumSteps = 16
+
+// mediumDepth is how many mean free paths into a medium light is gathered, past it less than a thousandth is left
+const mediumDepth = 6.9
+
+// SetFog fills the space outside every object with a medium. what is seen fades exponentially with distance,
+// and light from the lights scatters toward the eye on the way. the zero Medium clears it
+func (w *World) SetFog(m materials.Medium) {
+	w.fog = m
+}
+
+// mediumIn is the medium inside the shape a ray travelled through, the fog outside of everything
+func (w *World) mediumIn(s shapes.Shape) materials.Medium {
+	if s == nil {
+		return w.fog
+	}
+	return s.GetMaterial().Medium
+}
+
+// throughMedium is the light seen along distance of the ray through the medium:
+// the color behind it dimmed, plus the light scattered toward the eye on the way
+func (w *World) throughMedium(m materials.Medium, r geom.Ray, distance float64, behind colors.Color, rng *rand.Rand) colors.Color {
+	if !materials.IsParticipating(m) {
+		return behind
+	}
+	if t := materials.MediumTransmittance(m, distance); t > 0 {
+		behind = behind.MulBy(t)
+	} else {
+		behind = colors.Black()
+	}
+	return behind.Add(w.inScattered(m, r, distance, rng))
+}
+
+// inScattered is the light from the lights that the medium scatters toward the eye along distance of the ray.
+// it is gathered once at jittered steps with shadow rays, so occluders cast shafts through the medium.
+// light is not dimmed by the medium on its way to each step
+func (w *World) inScattered(m materials.Medium, r geom.Ray, distance float64, rng *rand.Rand) colors.Color {
+	length := r.Direction.Mag()
+	if m.Scattering <= 0 || len(w.lights) == 0 || length == 0 {
+		return colors.Black()
+	}
+	extinction := m.Absorption + m.Scattering
+	distance = math.Min(distance, mediumDepth/extinction)
+	step := distance / mediumSteps
+
+	sum := colors.Black()
+	offset := uniform(rng)
+	for i := 0; i < mediumSteps; i++ {
+		t := (float64(i) + offset) * step
+		p := r.Position(t / length)
+
+		light := colors.Black()
+		for _, l := range w.lights {
+			falloff := l.Falloff(p)
+			if falloff <= 0 {
+				continue
+			}
+			light = light.Add(l.GetIntensity().MulBy(falloff * IntensityAt(l, p, w)))
+		}
+		sum = sum.Add(light.MulBy(math.Exp(-extinction * t)))
+	}
+
+	// isotropic scattering of the pi * intensity arriving from each light, spread over the 4 pi sphere
+	return sum.Mul(m.Color).MulBy(m.Scattering * step / 4)
+}
@@ -0,0 +1,112 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// fogWorld looks down +z at a black wall 10 away, lit by a white sun from straight above
+func fogWorld() (*World, geom.Ray) {
+	w := NewWorld()
+	wall := shapes.NewPlane()
+	wall.SetTransform(geom.Translate(0, 0, 10).MulX4Matrix(geom.RotateX(math.Pi / 2)))
+	m := wall.GetMaterial()
+	m.Color = colors.Black()
+	m.Ambient = 0
+	m.Specular = 0
+	wall.SetMaterial(m)
+	w.AddObject(wall)
+	w.AddLight(shapes.NewDirectionalLight(geom.NewVector(0, -1, 0), 0, 1, colors.White(), nil))
+
+	return w, geom.RayWith(geom.ZeroPoint(), geom.NewVector(0, 0, 1))
+}
+
+func Test_Fog_FadesWithDistance(t *testing.T) {
+	w, r := fogWorld()
+	m := w.objects[0].GetMaterial()
+	m.Emission = colors.White()
+	w.objects[0].SetMaterial(m)
+	require.Equal(t, colors.White(), w.ColorAt(r, 1))
+
+	// absorbing only, nothing is scattered back
+	w.SetFog(materials.NewMedium(colors.White(), 0.1, 0))
+	requireColorInDelta(t, colors.White().MulBy(math.Exp(-1)), w.ColorAt(r, 1))
+
+	// everything past the fog is hidden
+	miss := geom.RayWith(geom.ZeroPoint(), geom.NewVector(0, 0, -1))
+	w.SetEnvironment(NewConstantEnvironment(colors.White()))
+	require.Equal(t, colors.Black(), w.ColorAt(miss, 1))
+
+	w.SetFog(materials.Medium{})
+	require.Equal(t, colors.White(), w.ColorAt(miss, 1))
+}
+
+func Test_Fog_ScattersLight(t *testing.T) {
+	w, r := fogWorld()
+	sigma := 0.1
+	w.SetFog(materials.NewMedium(colors.NewColor(1, 0.5, 0), 0, sigma))
+
+	// integral of the sun scattered toward the eye, dimmed on its way back over 10
+	expected := (1 - math.Exp(-sigma*10)) / 4
+	c := w.ColorAt(r, 1)
+
+	// one jittered pass is within half a step of the integral
+	require.InDelta(t, expected, c.R, expected*0.04)
+	require.InDelta(t, expected/2, c.G, expected*0.02)
+	require.Equal(t, 0.0, c.B)
+
+	// the steps are jittered with the rng of the render worker
+	c = WhittedIntegrator{}.ColorAt(w, r, 1, rand.New(rand.NewSource(3)))
+	require.Equal(t, c, WhittedIntegrator{}.ColorAt(w, r, 1, rand.New(rand.NewSource(3))))
+	require.NotEqual(t, c, WhittedIntegrator{}.ColorAt(w, r, 1, rand.New(rand.NewSource(4))))
+}
+
+func Test_Fog_LightShafts(t *testing.T) {
+	w, r := fogWorld()
+	sigma := 0.1
+	w.SetFog(materials.NewMedium(colors.White(), 0, sigma))
+
+	// a roof over the first half of the ray keeps the sun out of the fog there
+	roof := shapes.NewCube()
+	roof.SetTransform(geom.Translate(0, 2, 2.5).MulX4Matrix(geom.Scale(5, 0.1, 2.5)))
+	w.AddObject(roof)
+
+	expected := (math.Exp(-sigma*5) - math.Exp(-sigma*10)) / 4
+	c := w.ColorAt(r, 1)
+
+	require.InDelta(t, expected, c.R, expected*0.1)
+}
+
+func Test_Medium_FillsShape(t *testing.T) {
+	w, r := fogWorld()
+	sigma := 0.5
+	smoke := shapes.NewCube()
+	smoke.SetTransform(geom.Translate(0, 0, 5))
+	smoke.SetMaterial(materials.NewMediumMaterial(materials.NewMedium(colors.White(), 0, sigma)))
+	smoke.SetShadowless(true)
+	w.AddObject(smoke)
+
+	// scattered only inside the cube, 2 across
+	expected := (1 - math.Exp(-sigma*2)) / 4
+	c := w.ColorAt(r, 3)
+	require.InDelta(t, expected, c.R, expected*0.04)
+
+	w.Compile()
+	c = w.ColorAt(r, 3)
+	require.InDelta(t, expected, c.R, expected*0.04)
+
+	p := NewPathTracer()
+	rng := rand.New(rand.NewSource(1))
+	sum := 0.0
+	n := 2000
+	for i := 0; i < n; i++ {
+		sum += p.ColorAt(w, r, 3, rng).R
+	}
+	require.InDelta(t, expected, sum/float64(n), expected*0.02)
+}
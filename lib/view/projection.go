@@ -0,0 +1,96 @@
+package view
+
+import (
+	"fmt"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"math"
+)
+
+// Projection maps positions on the image to rays in camera space.
+// camera space looks down -z with +y up, and +x to the left of the image like the perspective camera.
+// a nil Projection on the Camera is the perspective projection from Camera.Fov
+type Projection interface {
+	Name() string
+	// CameraRay gives the ray through image position u, v in [0, 1] from the top left corner.
+	// aspect is the image width over height.
+	// ok is false where the projection covers no directions, like outside the fisheye circle
+	CameraRay(u, v, aspect float64) (origin, direction geom.Tuple, ok bool)
+}
+
+// Orthographic fires parallel rays from a rectangle HalfWidth*2 wide, for technical views without perspective
+type Orthographic struct {
+	HalfWidth float64
+}
+
+func (o Orthographic) Name() string {
+	return "orthographic"
+}
+
+func (o Orthographic) CameraRay(u, v, aspect float64) (geom.Tuple, geom.Tuple, bool) {
+	x := (1 - 2*u) * o.HalfWidth
+	y := (1 - 2*v) * o.HalfWidth / aspect
+	return geom.NewPoint(x, y, 0), geom.NewVector(0, 0, -1), true
+}
+
+// Equirectangular covers every direction. longitude runs along the width and latitude along the height,
+// so a 2:1 image can be used as an environment map
+type Equirectangular struct{}
+
+func (e Equirectangular) Name() string {
+	return "equirectangular"
+}
+
+func (e Equirectangular) CameraRay(u, v, aspect float64) (geom.Tuple, geom.Tuple, bool) {
+	lon := (u - 0.5) * 2 * math.Pi
+	lat := (0.5 - v) * math.Pi
+	return geom.ZeroPoint(), geom.NewVector(-math.Sin(lon)*math.Cos(lat), math.Sin(lat), -math.Cos(lon)*math.Cos(lat)), true
+}
+
+// Fisheye is an equidistant fisheye, the angle from the view direction grows linearly to Fov/2 at the edge of
+// a circle that fills the shorter side of the image
+type Fisheye struct {
+	Fov float64
+}
+
+func (f Fisheye) Name() string {
+	return "fisheye"
+}
+
+func (f Fisheye) CameraRay(u, v, aspect float64) (geom.Tuple, geom.Tuple, bool) {
+	x := 1 - 2*u
+	y := 1 - 2*v
+	if aspect >= 1 {
+		x *= aspect
+	} else {
+		y /= aspect
+	}
+
+	r := math.Sqrt(x*x + y*y)
+	if r > 1 {
+		return geom.Tuple{}, geom.Tuple{}, false
+	}
+	theta := r * f.Fov / 2
+	phi := math.Atan2(y, x)
+	return geom.ZeroPoint(), geom.NewVector(math.Sin(theta)*math.Cos(phi), math.Sin(theta)*math.Sin(phi), -math.Cos(theta)), true
+}
+
+// Projections are the names accepted by NewProjection
+var Projections = []string{"perspective", "orthographic", "equirectangular", "fisheye"}
+
+// NewProjection builds a named projection framed like the perspective view of the camera.
+// perspective is the nil Projection
+func NewProjection(name string, c Camera) (Projection, error) {
+	switch name {
+	case "perspective":
+		return nil, nil
+	case "orthographic":
+		// as wide as the perspective view at the point the camera looks at
+		return Orthographic{HalfWidth: math.Tan(c.Fov/2) * c.lookDistance}, nil
+	case "equirectangular":
+		return Equirectangular{}, nil
+	case "fisheye":
+		return Fisheye{Fov: math.Pi}, nil
+	default:
+		return nil, fmt.Errorf("unknown projection %q", name)
+	}
+}
@@ -0,0 +1,93 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func Test_Projection_CameraRay(t *testing.T) {
+	type args struct {
+		p         Projection
+		u, v      float64
+		aspect    float64
+		origin    geom.Tuple
+		direction geom.Tuple
+		ok        bool
+	}
+
+	tests := []args{
+		{Orthographic{HalfWidth: 2}, 0.5, 0.5, 2, geom.ZeroPoint(), geom.NewVector(0, 0, -1), true},
+		{Orthographic{HalfWidth: 2}, 0, 0, 2, geom.NewPoint(2, 1, 0), geom.NewVector(0, 0, -1), true},
+		{Orthographic{HalfWidth: 2}, 1, 1, 2, geom.NewPoint(-2, -1, 0), geom.NewVector(0, 0, -1), true},
+		{Equirectangular{}, 0.5, 0.5, 2, geom.ZeroPoint(), geom.NewVector(0, 0, -1), true},
+		{Equirectangular{}, 0.75, 0.5, 2, geom.ZeroPoint(), geom.NewVector(-1, 0, 0), true},
+		{Equirectangular{}, 0, 0.5, 2, geom.ZeroPoint(), geom.NewVector(0, 0, 1), true},
+		{Equirectangular{}, 0.3, 0, 2, geom.ZeroPoint(), geom.NewVector(0, 1, 0), true},
+		{Fisheye{Fov: math.Pi}, 0.5, 0.5, 1, geom.ZeroPoint(), geom.NewVector(0, 0, -1), true},
+		{Fisheye{Fov: math.Pi}, 0.5, 0, 1, geom.ZeroPoint(), geom.NewVector(0, 1, 0), true},
+		{Fisheye{Fov: math.Pi}, 1, 0.5, 1, geom.ZeroPoint(), geom.NewVector(-1, 0, 0), true},
+		// circle fills the height of a wide image
+		{Fisheye{Fov: math.Pi}, 0.75, 0.5, 2, geom.ZeroPoint(), geom.NewVector(-1, 0, 0), true},
+		{Fisheye{Fov: math.Pi}, 0, 0, 1, geom.Tuple{}, geom.Tuple{}, false},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			origin, direction, ok := tt.p.CameraRay(tt.u, tt.v, tt.aspect)
+
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.origin.RoundTo(9), origin.RoundTo(9))
+			require.Equal(t, tt.direction.RoundTo(9), direction.RoundTo(9))
+		})
+	}
+}
+
+func Test_NewProjection(t *testing.T) {
+	c := NewCameraAt(100, 50, math.Pi/2, geom.NewPoint(0, 0, -5), geom.ZeroPoint())
+
+	for _, name := range Projections {
+		p, err := NewProjection(name, c)
+		require.NoError(t, err)
+		if name == "perspective" {
+			require.Nil(t, p)
+		} else {
+			require.Equal(t, name, p.Name())
+		}
+	}
+
+	p, err := NewProjection("orthographic", c)
+	require.NoError(t, err)
+	require.InDelta(t, 5, p.(Orthographic).HalfWidth, 1e-9)
+
+	_, err = NewProjection("panini", c)
+	require.Error(t, err)
+}
+
+func Test_Camera_OrthographicTransformed(t *testing.T) {
+	c := NewCameraAt(11, 11, math.Pi/2, geom.NewPoint(0, 0, -5), geom.ZeroPoint()).WithProjection(Orthographic{HalfWidth: 1})
+
+	center := c.rayForPixel(5, 5)
+	corner := c.rayForPixel(0, 0)
+
+	require.Equal(t, geom.NewPoint(0, 0, -5), center.Origin.RoundTo(9))
+	require.Equal(t, geom.NewVector(0, 0, 1), center.Direction.RoundTo(9))
+	require.Equal(t, center.Direction.RoundTo(9), corner.Direction.RoundTo(9))
+	// image x runs along world -x when looking down +z
+	require.InDelta(t, -10./11, corner.Origin.X, 1e-9)
+	require.InDelta(t, 10./11, corner.Origin.Y, 1e-9)
+}
+
+func Test_Camera_RenderFisheye(t *testing.T) {
+	w := defaultWorld()
+	c := NewCameraAt(11, 11, math.Pi/2, geom.NewPoint(0, 0, -5), geom.ZeroPoint()).WithProjection(Fisheye{Fov: math.Pi})
+
+	image := c.Render(w, 0, 1)
+
+	// the sphere is straight ahead, the corners are outside the fisheye circle
+	require.Equal(t, colors.NewColor(0.38066, 0.47583, 0.2855), image.GetPixel(5, 5).RoundTo(5))
+	require.Equal(t, colors.Black(), image.GetPixel(0, 0))
+}
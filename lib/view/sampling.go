@@ -0,0 +1,178 @@
+package view
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// SamplePattern places the samples of one pixel
+type SamplePattern uint8
+
+const (
+	// SampleGrid is a regular grid of cell centers, one sample is the pixel center
+	SampleGrid SamplePattern = iota
+	// SampleJittered places every sample uniformly at random
+	SampleJittered
+	// SampleStratified jitters one sample inside each cell of a grid
+	SampleStratified
+	// SampleAdaptive fires one ray per pixel, then subdivides pixels that contrast with their neighbours
+	SampleAdaptive
+)
+
+func (p SamplePattern) String() string {
+	switch p {
+	case SampleGrid:
+		return "grid"
+	case SampleJittered:
+		return "jittered"
+	case SampleStratified:
+		return "stratified"
+	case SampleAdaptive:
+		return "adaptive"
+	default:
+		return fmt.Sprintf("SamplePattern(%d)", p)
+	}
+}
+
+func ParseSamplePattern(s string) (SamplePattern, error) {
+	for _, p := range []SamplePattern{SampleGrid, SampleJittered, SampleStratified, SampleAdaptive} {
+		if s == p.String() {
+			return p, nil
+		}
+	}
+	return SampleGrid, fmt.Errorf("unknown sample pattern %q", s)
+}
+
+// Filter weights samples by their offset from the pixel center, in pixels.
+// samples are spread over the filter radius, so wider filters blend in neighbouring pixels.
+type Filter struct {
+	Name   string
+	Radius float64
+	weight func(x, y float64) float64
+}
+
+// the zero Filter is a box filter
+func (f Filter) orDefault() Filter {
+	if f.weight == nil {
+		return BoxFilter()
+	}
+	return f
+}
+
+func BoxFilter() Filter {
+	return Filter{Name: "box", Radius: 0.5, weight: func(x, y float64) float64 { return 1 }}
+}
+
+func TentFilter(radius float64) Filter {
+	return Filter{Name: "tent", Radius: radius, weight: func(x, y float64) float64 {
+		return math.Max(0, radius-math.Abs(x)) * math.Max(0, radius-math.Abs(y))
+	}}
+}
+
+// GaussianFilter is shifted down so it reaches zero at the radius
+func GaussianFilter(radius, alpha float64) Filter {
+	edge := math.Exp(-alpha * radius * radius)
+	g := func(v float64) float64 {
+		return math.Max(0, math.Exp(-alpha*v*v)-edge)
+	}
+	return Filter{Name: "gaussian", Radius: radius, weight: func(x, y float64) float64 {
+		return g(x) * g(y)
+	}}
+}
+
+// MitchellFilter is the Mitchell-Netravali cubic with radius 2. B = C = 1/3 is the recommended choice
+func MitchellFilter(b, c float64) Filter {
+	m := func(v float64) float64 {
+		v = math.Abs(v)
+		if v < 1 {
+			return ((12-9*b-6*c)*v*v*v + (-18+12*b+6*c)*v*v + (6 - 2*b)) / 6
+		}
+		if v < 2 {
+			return ((-b-6*c)*v*v*v + (6*b+30*c)*v*v + (-12*b-48*c)*v + (8*b + 24*c)) / 6
+		}
+		return 0
+	}
+	return Filter{Name: "mitchell", Radius: 2, weight: func(x, y float64) float64 {
+		return m(x) * m(y)
+	}}
+}
+
+// Filters are the named reconstruction filters with their default parameters
+var Filters = []Filter{
+	BoxFilter(),
+	TentFilter(1),
+	GaussianFilter(1.5, 2),
+	MitchellFilter(1./3, 1./3),
+}
+
+func ParseFilter(name string) (Filter, error) {
+	for _, f := range Filters {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+	return Filter{}, fmt.Errorf("unknown filter %q", name)
+}
+
+// Sampling configures anti-aliasing. the zero value fires one ray through each pixel center
+type Sampling struct {
+	SamplesPerPixel int
+	Pattern         SamplePattern
+	Filter          Filter
+
+	// adaptive sampling only, it ignores SamplesPerPixel and Filter.
+	// a pixel is subdivided when any color channel differs from a neighbour by more than Threshold
+	Threshold float64
+	MaxDepth  int
+
+	// Seed seeds the random numbers of the render workers, 0 seeds them from the clock.
+	// renders accumulated over several passes give each pass its own seed
+	Seed int64
+}
+
+// offsets returns sample positions relative to the pixel center, in pixels
+func (s Sampling) offsets(rng *rand.Rand) [][2]float64 {
+	n := s.SamplesPerPixel
+	if n < 1 {
+		n = 1
+	}
+	radius := s.Filter.orDefault().Radius
+
+	var uvs [][2]float64
+	switch s.Pattern {
+	case SampleJittered:
+		uvs = make([][2]float64, n)
+		for i := range uvs {
+			uvs[i] = [2]float64{rng.Float64(), rng.Float64()}
+		}
+	default:
+		cols, rows := gridSize(n)
+		uvs = make([][2]float64, 0, n)
+		for j := 0; j < rows; j++ {
+			for i := 0; i < cols; i++ {
+				du, dv := 0.5, 0.5
+				if s.Pattern == SampleStratified {
+					du, dv = rng.Float64(), rng.Float64()
+				}
+				uvs = append(uvs, [2]float64{(float64(i) + du) / float64(cols), (float64(j) + dv) / float64(rows)})
+			}
+		}
+	}
+
+	for i := range uvs {
+		uvs[i][0] = (2*uvs[i][0] - 1) * radius
+		uvs[i][1] = (2*uvs[i][1] - 1) * radius
+	}
+	return uvs
+}
+
+// gridSize picks the grid closest to square with exactly n cells.
+// a prime n becomes a single row
+func gridSize(n int) (cols, rows int) {
+	rows = int(math.Sqrt(float64(n)))
+	for n%rows != 0 {
+		rows--
+	}
+	return n / rows, rows
+}
@@ -0,0 +1,167 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
+	"github.com/robkau/go-raytrace/lib/shapes"
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func Test_Sampling_Offsets(t *testing.T) {
+	type args struct {
+		s      Sampling
+		expect int
+		radius float64
+	}
+
+	tests := []args{
+		{Sampling{}, 1, 0.5},
+		{Sampling{SamplesPerPixel: 4}, 4, 0.5},
+		{Sampling{SamplesPerPixel: 5, Pattern: SampleGrid}, 5, 0.5},
+		{Sampling{SamplesPerPixel: 6, Pattern: SampleGrid}, 6, 0.5},
+		{Sampling{SamplesPerPixel: 7, Pattern: SampleStratified}, 7, 0.5},
+		{Sampling{SamplesPerPixel: 12, Pattern: SampleStratified, Filter: TentFilter(1)}, 12, 1},
+		{Sampling{SamplesPerPixel: 5, Pattern: SampleJittered}, 5, 0.5},
+		{Sampling{SamplesPerPixel: 16, Pattern: SampleStratified, Filter: MitchellFilter(1./3, 1./3)}, 16, 2},
+		{Sampling{SamplesPerPixel: 9, Pattern: SampleStratified, Filter: TentFilter(1)}, 9, 1},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			offsets := tt.s.offsets(rand.New(rand.NewSource(1)))
+
+			require.Len(t, offsets, tt.expect)
+			for _, o := range offsets {
+				require.LessOrEqual(t, math.Abs(o[0]), tt.radius)
+				require.LessOrEqual(t, math.Abs(o[1]), tt.radius)
+			}
+		})
+	}
+}
+
+func Test_Sampling_GridIsCentered(t *testing.T) {
+	require.Equal(t, [][2]float64{{0, 0}}, Sampling{}.offsets(nil))
+	require.Equal(t, [][2]float64{{-0.25, -0.25}, {0.25, -0.25}, {-0.25, 0.25}, {0.25, 0.25}}, Sampling{SamplesPerPixel: 4}.offsets(nil))
+}
+
+func Test_Sampling_StratifiedOnePerCell(t *testing.T) {
+	offsets := Sampling{SamplesPerPixel: 4, Pattern: SampleStratified}.offsets(rand.New(rand.NewSource(1)))
+
+	cells := map[[2]bool]bool{}
+	for _, o := range offsets {
+		cells[[2]bool{o[0] < 0, o[1] < 0}] = true
+	}
+	require.Len(t, cells, 4)
+}
+
+func Test_Sampling_StratifiedNonSquare(t *testing.T) {
+	// 6 samples stratify a 3x2 grid, each cell holds exactly one
+	offsets := Sampling{SamplesPerPixel: 6, Pattern: SampleStratified}.offsets(rand.New(rand.NewSource(1)))
+
+	cells := map[[2]int]bool{}
+	for _, o := range offsets {
+		cells[[2]int{int((o[0] + 0.5) * 3), int((o[1] + 0.5) * 2)}] = true
+	}
+	require.Len(t, offsets, 6)
+	require.Len(t, cells, 6)
+}
+
+func Test_Filters(t *testing.T) {
+	type args struct {
+		f      Filter
+		x, y   float64
+		expect float64
+	}
+
+	tests := []args{
+		{BoxFilter(), 0, 0, 1},
+		{BoxFilter(), 0.4, -0.4, 1},
+		{Filter{}.orDefault(), 0.4, -0.4, 1},
+		{TentFilter(1), 0, 0, 1},
+		{TentFilter(1), 0.5, 0, 0.5},
+		{TentFilter(1), 1, 0, 0},
+		{GaussianFilter(1.5, 2), 1.5, 0, 0},
+		{GaussianFilter(1.5, 2), 0, 0, math.Pow(1-math.Exp(-4.5), 2)},
+		{MitchellFilter(1./3, 1./3), 0, 0, math.Pow(8./9, 2)},
+		{MitchellFilter(1./3, 1./3), 2, 0, 0},
+		{MitchellFilter(1./3, 1./3), 1, 0, 1. / 18 * 8. / 9},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			require.InDelta(t, tt.expect, tt.f.weight(tt.x, tt.y), 1e-9)
+		})
+	}
+}
+
+func Test_ParseSampling(t *testing.T) {
+	for _, f := range Filters {
+		parsed, err := ParseFilter(f.Name)
+		require.NoError(t, err)
+		require.Equal(t, f.Name, parsed.Name)
+	}
+	_, err := ParseFilter("lanczos")
+	require.Error(t, err)
+
+	for _, p := range []SamplePattern{SampleGrid, SampleJittered, SampleStratified} {
+		parsed, err := ParseSamplePattern(p.String())
+		require.NoError(t, err)
+		require.Equal(t, p, parsed)
+	}
+	_, err = ParseSamplePattern("poisson")
+	require.Error(t, err)
+}
+
+func Test_RayForPixelOffset(t *testing.T) {
+	c := NewCamera(201, 101, math.Pi/2)
+
+	require.Equal(t, c.rayForPixel(100, 50), c.rayForPixelOffset(100, 50, 0.5, 0.5, nil))
+	require.Equal(t, c.rayForPixelOffset(100, 50, 1, 0.5, nil), c.rayForPixelOffset(101, 50, 0, 0.5, nil))
+}
+
+func Test_ColorForPixel_AveragesEdge(t *testing.T) {
+	// the left edge of a white cube runs through the middle of the center pixel
+	w := NewWorld()
+	w.AddPointLight(shapes.NewPointLight(geom.NewPoint(0, 0, -10), colors.White()))
+	cube := shapes.NewCube()
+	cube.SetTransform(geom.Translate(5, 0, 0).MulX4Matrix(geom.Scale(5, 5, 1)))
+	m := materials.NewMaterial()
+	m.Color = colors.White()
+	m.Ambient = 1
+	m.Diffuse = 0
+	m.Specular = 0
+	cube.SetMaterial(m)
+	w.AddObject(cube)
+	c := NewCamera(3, 3, math.Pi/2)
+	c.Transform = geom.ViewTransform(geom.NewPoint(0, 0, -5), geom.ZeroPoint(), geom.UpVector())
+
+	hit, _ := c.colorForPixel(w, 2, 1, 0, nil)
+	miss, _ := c.colorForPixel(w, 0, 1, 0, nil)
+	require.Equal(t, colors.White(), hit)
+	require.Equal(t, colors.Black(), miss)
+
+	c = c.WithSampling(Sampling{SamplesPerPixel: 16})
+	averaged, samples := c.colorForPixel(w, 1, 1, 0, rand.New(rand.NewSource(1)))
+	require.Equal(t, 16, samples)
+	require.InDelta(t, 0.5, averaged.R, 1e-9)
+}
+
+func Test_Camera_RenderNonSquare(t *testing.T) {
+	w := defaultWorld()
+	c := NewCamera(7, 3, math.Pi/2)
+	c.Transform = geom.ViewTransform(geom.NewPoint(0, 0, -5), geom.ZeroPoint(), geom.UpVector())
+
+	image := c.Render(w, 0, 2)
+
+	// every pixel is rendered, the center one hits the sphere
+	width, height := image.GetSize()
+	require.Equal(t, 7, width)
+	require.Equal(t, 3, height)
+	require.Equal(t, colors.NewColor(0.38066, 0.47583, 0.2855), image.GetPixel(3, 1).RoundTo(5))
+	require.Equal(t, colors.Black(), image.GetPixel(0, 0))
+}
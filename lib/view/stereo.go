@@ -0,0 +1,126 @@
+package view
+
+import (
+	"fmt"
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/view/canvas"
+)
+
+// StereoLayout arranges the images of both eyes in one canvas
+type StereoLayout uint8
+
+const (
+	// StereoSideBySide puts the left eye on the left half
+	StereoSideBySide StereoLayout = iota
+	// StereoOverUnder puts the left eye on the top half
+	StereoOverUnder
+	// StereoAnaglyph takes red from the left eye and green and blue from the right eye, for red/cyan glasses
+	StereoAnaglyph
+)
+
+func (l StereoLayout) String() string {
+	switch l {
+	case StereoSideBySide:
+		return "side-by-side"
+	case StereoOverUnder:
+		return "over-under"
+	case StereoAnaglyph:
+		return "anaglyph"
+	default:
+		return fmt.Sprintf("StereoLayout(%d)", l)
+	}
+}
+
+func ParseStereoLayout(s string) (StereoLayout, error) {
+	for _, l := range []StereoLayout{StereoSideBySide, StereoOverUnder, StereoAnaglyph} {
+		if s == l.String() {
+			return l, nil
+		}
+	}
+	return StereoSideBySide, fmt.Errorf("unknown stereo layout %q", s)
+}
+
+// StereoRig renders a left and right eye pair from one camera.
+// the eyes look in parallel and their image planes are shifted to meet at the convergence distance,
+// so there is no vertical parallax like a toed-in pair would have
+type StereoRig struct {
+	// Camera is the center between the eyes, each eye renders at its size
+	Camera Camera
+	// Interocular is the distance between the eyes. zero uses 1/30 of the distance the camera looks at
+	Interocular float64
+	// Convergence is the distance that appears on the screen plane. zero uses the distance the camera looks at
+	Convergence float64
+	Layout      StereoLayout
+}
+
+func NewStereoRig(c Camera, interocular, convergence float64, layout StereoLayout) StereoRig {
+	return StereoRig{
+		Camera:      c,
+		Interocular: interocular,
+		Convergence: convergence,
+		Layout:      layout,
+	}
+}
+
+// Eyes returns the cameras for the left and right eye
+func (s StereoRig) Eyes() (Camera, Camera) {
+	io := s.Interocular
+	if io <= 0 {
+		io = s.Camera.lookDistance / 30
+	}
+	convergence := s.Convergence
+	if convergence <= 0 {
+		convergence = s.Camera.lookDistance
+	}
+
+	// camera space +x is to the left of the image
+	return s.Camera.withEye(io/2, convergence), s.Camera.withEye(-io/2, convergence)
+}
+
+func (c Camera) withEye(offset, convergence float64) Camera {
+	c.eyeOffset = offset
+	// move the image plane back so both eyes see the same point at the convergence distance
+	c.eyeShift = -offset / convergence
+	return c
+}
+
+// Size is the size of the composed canvas
+func (s StereoRig) Size() (int, int) {
+	switch s.Layout {
+	case StereoSideBySide:
+		return s.Camera.HSize * 2, s.Camera.VSize
+	case StereoOverUnder:
+		return s.Camera.HSize, s.Camera.VSize * 2
+	default:
+		return s.Camera.HSize, s.Camera.VSize
+	}
+}
+
+// Compose combines the images of both eyes with the layout
+func (s StereoRig) Compose(left, right *canvas.Canvas) *canvas.Canvas {
+	width, height := left.GetSize()
+	composed := canvas.NewCanvas(s.Size())
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			l := left.GetPixel(x, y)
+			r := right.GetPixel(x, y)
+			switch s.Layout {
+			case StereoSideBySide:
+				composed.SetPixel(x, y, l)
+				composed.SetPixel(x+width, y, r)
+			case StereoOverUnder:
+				composed.SetPixel(x, y, l)
+				composed.SetPixel(x, y+height, r)
+			case StereoAnaglyph:
+				composed.SetPixel(x, y, colors.NewColor(l.R, r.G, r.B))
+			}
+		}
+	}
+	return composed
+}
+
+// Render blocks until both eyes are rendered and composed
+func (s StereoRig) Render(w *World, rayBounces int, numGoRoutines int) *canvas.Canvas {
+	left, right := s.Eyes()
+	return s.Compose(left.Render(w, rayBounces, numGoRoutines), right.Render(w, rayBounces, numGoRoutines))
+}
@@ -0,0 +1,116 @@
+package view
+
+import (
+	"github.com/robkau/go-raytrace/lib/colors"
+	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/view/canvas"
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func Test_StereoRig_Eyes(t *testing.T) {
+	type args struct {
+		interocular float64
+		convergence float64
+		eyeX        float64
+		meetZ       float64
+	}
+
+	tests := []args{
+		{0.5, 2, 0.25, -3},
+		// defaults come from the look distance
+		{0, 0, 5. / 60, 0},
+		{0.5, 0, 0.25, 0},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			// looking down +z, so camera space +x is world -x
+			c := NewCameraAt(201, 101, math.Pi/2, geom.NewPoint(0, 0, -5), geom.ZeroPoint())
+			left, right := NewStereoRig(c, tt.interocular, tt.convergence, StereoSideBySide).Eyes()
+
+			lr := left.rayForPixel(100, 50)
+			rr := right.rayForPixel(100, 50)
+
+			require.Equal(t, geom.NewPoint(-tt.eyeX, 0, -5).RoundTo(9), lr.Origin.RoundTo(9))
+			require.Equal(t, geom.NewPoint(tt.eyeX, 0, -5).RoundTo(9), rr.Origin.RoundTo(9))
+
+			// the center rays cross at the convergence distance
+			lp := lr.Position((tt.meetZ - lr.Origin.Z) / lr.Direction.Z)
+			rp := rr.Position((tt.meetZ - rr.Origin.Z) / rr.Direction.Z)
+			require.Equal(t, geom.NewPoint(0, 0, tt.meetZ), lp.RoundTo(9))
+			require.Equal(t, geom.NewPoint(0, 0, tt.meetZ), rp.RoundTo(9))
+
+			// without vertical parallax
+			ld := left.rayForPixel(0, 0).Direction
+			rd := right.rayForPixel(0, 0).Direction
+			require.InDelta(t, ld.Y/ld.Z, rd.Y/rd.Z, 1e-9)
+		})
+	}
+}
+
+func Test_StereoRig_Compose(t *testing.T) {
+	type args struct {
+		layout StereoLayout
+		width  int
+		height int
+		pixels map[[2]int]colors.Color
+	}
+
+	l := colors.NewColor(0.1, 0.2, 0.3)
+	r := colors.NewColor(0.4, 0.5, 0.6)
+	tests := []args{
+		{StereoSideBySide, 6, 2, map[[2]int]colors.Color{{0, 0}: l, {2, 1}: l, {3, 0}: r, {5, 1}: r}},
+		{StereoOverUnder, 3, 4, map[[2]int]colors.Color{{0, 0}: l, {2, 1}: l, {0, 2}: r, {2, 3}: r}},
+		{StereoAnaglyph, 3, 2, map[[2]int]colors.Color{{0, 0}: colors.NewColor(0.1, 0.5, 0.6), {2, 1}: colors.NewColor(0.1, 0.5, 0.6)}},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			rig := NewStereoRig(NewCamera(3, 2, math.Pi/2), 0, 0, tt.layout)
+			left := canvas.NewCanvas(3, 2)
+			right := canvas.NewCanvas(3, 2)
+			for y := 0; y < 2; y++ {
+				for x := 0; x < 3; x++ {
+					left.SetPixel(x, y, l)
+					right.SetPixel(x, y, r)
+				}
+			}
+
+			composed := rig.Compose(left, right)
+
+			width, height := composed.GetSize()
+			require.Equal(t, tt.width, width)
+			require.Equal(t, tt.height, height)
+			for p, c := range tt.pixels {
+				require.Equal(t, c, composed.GetPixel(p[0], p[1]))
+			}
+		})
+	}
+}
+
+func Test_ParseStereoLayout(t *testing.T) {
+	for _, l := range []StereoLayout{StereoSideBySide, StereoOverUnder, StereoAnaglyph} {
+		parsed, err := ParseStereoLayout(l.String())
+		require.NoError(t, err)
+		require.Equal(t, l, parsed)
+	}
+	_, err := ParseStereoLayout("checkerboard")
+	require.Error(t, err)
+}
+
+func Test_StereoRig_Render(t *testing.T) {
+	w := defaultWorld()
+	c := NewCameraAt(11, 11, math.Pi/2, geom.NewPoint(0, 0, -5), geom.ZeroPoint())
+
+	image := NewStereoRig(c, 0.1, 0, StereoSideBySide).Render(w, 0, 1)
+
+	width, height := image.GetSize()
+	require.Equal(t, 22, width)
+	require.Equal(t, 11, height)
+	// both eyes converge on the sphere in the middle of their half
+	require.NotEqual(t, colors.Black(), image.GetPixel(5, 5))
+	require.NotEqual(t, colors.Black(), image.GetPixel(16, 5))
+}
@@ -6,22 +6,60 @@ import (
 	"github.com/robkau/coordinate_supplier"
 	"github.com/robkau/go-raytrace/lib/colors"
 	"github.com/robkau/go-raytrace/lib/geom"
+	"github.com/robkau/go-raytrace/lib/materials"
 	"github.com/robkau/go-raytrace/lib/shapes"
 	"math"
+	"math/rand"
 	"sync"
+	"time"
 )
 
 type World struct {
-	objects     []shapes.Shape
-	pointLights []shapes.PointLight
-	areaLights  []shapes.AreaLight
+	objects []shapes.Shape
+	lights  []shapes.Light
+
+	// nil until compiled
+	bvh *shapes.FlatBVH
+
+	// emissive primitives found in the objects
+	emitters   []emitter
+	emitterIds map[string]bool
+	// an object absorbs or scatters light inside it, so every hit needs the medium the ray came through
+	media bool
+	// fills the space outside every object, the zero value is vacuum
+	fog materials.Medium
+
+	// splits groups when divided and the compiled bvh
+	bvhBuilder shapes.BVHBuilder
+
+	// nil is black
+	environment Environment
+	// directions the whitted integrator gathers environment light from per hit
+	environmentSamples int
+
+	// rays traced from the first rough mirror or glass surface along a whitted ray
+	glossySamples int
 }
 
+// trace is what a whitted ray carries down through its reflections and refractions
+type trace struct {
+	// glossySamples is how many rays the next rough surface spreads over
+	glossySamples int
+	// wavelength in nanometers once a dispersive surface split the light, 0 while it is still white
+	wavelength float64
+	// rng belongs to the render worker tracing the ray
+	rng *rand.Rand
+}
+
+// shadingSeed seeds the exported shading calls, which have no render worker rng, so they repeat exactly
+const shadingSeed = 1
+
 func NewWorld() *World {
 	return &World{
-		objects:     []shapes.Shape{},
-		pointLights: []shapes.PointLight{},
-		areaLights:  []shapes.AreaLight{},
+		objects:       []shapes.Shape{},
+		lights:        []shapes.Light{},
+		emitterIds:    map[string]bool{},
+		glossySamples: DefaultGlossySamples,
 	}
 }
 
@@ -46,19 +84,39 @@ func defaultWorld() *World {
 	return w
 }
 
+// AddObject drops the compiled bvh, so the world is intersected object by object until Compile or Divide runs again.
+// shapes must not be transformed or have children added once the world is compiled, call Compile after changing them
 func (w *World) AddObject(s shapes.Shape) {
 	w.objects = append(w.objects, s)
+	w.bvh = nil
+	w.addEmitters(collectEmitters(s))
+	w.media = w.media || hasMedia(s)
+}
+
+func (w *World) addEmitters(es []emitter) {
+	for _, e := range es {
+		w.emitters = append(w.emitters, e)
+		w.emitterIds[e.s.Id()] = true
+	}
+}
+
+func (w *World) AddLight(l shapes.Light) {
+	w.lights = append(w.lights, l)
 }
 
 func (w *World) AddPointLight(l shapes.PointLight) {
-	w.pointLights = append(w.pointLights, l)
+	w.AddLight(l)
 }
 
 func (w *World) AddAreaLight(l shapes.AreaLight) {
-	w.areaLights = append(w.areaLights, l)
+	w.AddLight(l)
 }
 
 func (w *World) Intersect(r geom.Ray) *shapes.Intersections {
+	if w.bvh != nil {
+		return w.bvh.Intersect(r)
+	}
+
 	is := shapes.NewIntersections()
 	for _, s := range w.objects {
 		xs := s.Intersect(r)
@@ -68,20 +126,24 @@ func (w *World) Intersect(r geom.Ray) *shapes.Intersections {
 }
 
 func (w *World) ShadeHit(c shapes.IntersectionComputed, remaining int) colors.Color {
-	col := colors.NewColor(0, 0, 0)
-
-	for _, l := range w.pointLights {
-		col = col.Add(shapes.Lighting(c.Object.GetMaterial(), c.Object, l, c.OverPoint, c.Eyev, c.Normalv, IntensityAt(l, c.OverPoint, w)))
-	}
+	return w.shadeHit(c, remaining, w.seededTrace())
+}
 
-	for _, l := range w.areaLights {
-		col = col.Add(shapes.Lighting(c.Object.GetMaterial(), c.Object, l, c.OverPoint, c.Eyev, c.Normalv, IntensityAtAreaLight(l, c.OverPoint, w)))
+func (w *World) shadeHit(c shapes.IntersectionComputed, remaining int, tr trace) colors.Color {
+	m := c.Object.GetMaterial()
+	lit := m
+	if w.environmentLit() && c.Object.GetShaded() {
+		// the environment light is what ambient approximates
+		lit.Ambient = 0
 	}
+	col := w.directLight(c, lit).
+		Add(m.Emission).
+		Add(w.emittedLight(c, m, whittedEmitterSamples, tr.rng)).
+		Add(w.environmentLight(c, m, w.environmentSamples, tr.rng))
 
-	reflected := w.ReflectedColor(c, remaining)
-	refracted := w.RefractedColor(c, remaining)
+	reflected := w.reflectedColor(c, remaining, tr)
+	refracted := w.refractedColor(c, remaining, tr)
 
-	m := c.Object.GetMaterial()
 	if m.Reflective > 0 && m.Transparency > 0 {
 		// todo scale by transparency?
 		reflectance := c.Schlick()
@@ -91,7 +153,21 @@ func (w *World) ShadeHit(c shapes.IntersectionComputed, remaining int) colors.Co
 	return col.Add(reflected).Add(refracted)
 }
 
+// directLight is the Phong lighting from every light, with shadows
+func (w *World) directLight(c shapes.IntersectionComputed, m materials.Material) colors.Color {
+	col := colors.NewColor(0, 0, 0)
+
+	for _, l := range w.lights {
+		col = col.Add(shapes.Lighting(m, c.Object, l, c.OverPoint, c.Eyev, c.Normalv, IntensityAt(l, c.OverPoint, w)))
+	}
+	return col
+}
+
 func (w *World) ReflectedColor(c shapes.IntersectionComputed, remaining int) colors.Color {
+	return w.reflectedColor(c, remaining, w.seededTrace())
+}
+
+func (w *World) reflectedColor(c shapes.IntersectionComputed, remaining int, tr trace) colors.Color {
 	col := colors.NewColor(0, 0, 0)
 
 	if remaining <= 0 {
@@ -102,39 +178,124 @@ func (w *World) ReflectedColor(c shapes.IntersectionComputed, remaining int) col
 		return col
 	}
 
-	reflectRay := geom.RayWith(c.OverPoint, c.Reflectv)
-	col = w.ColorAt(reflectRay, remaining-1)
+	if c.Object.GetMaterial().Roughness > 0 {
+		col = w.glossyColor(remaining, tr, func() (geom.Ray, bool) {
+			direction, ok := reflectDirection(c, tr.rng)
+			return geom.RayWith(c.OverPoint, direction), ok
+		})
+	} else {
+		reflectRay := geom.RayWith(c.OverPoint, c.Reflectv)
+		col = w.colorAt(reflectRay, remaining-1, tr)
+	}
 
 	return col.MulBy(c.Object.GetMaterial().Reflective)
 }
 
 func (w *World) RefractedColor(c shapes.IntersectionComputed, remaining int) colors.Color {
+	return w.refractedColor(c, remaining, w.seededTrace())
+}
+
+func (w *World) refractedColor(c shapes.IntersectionComputed, remaining int, tr trace) colors.Color {
 	col := colors.NewColor(0, 0, 0)
 	if remaining == 0 || // limited recursion
 		c.Object.GetMaterial().Transparency == 0 { // opaque material
 		return col
 	}
 
-	nRatio := c.N1 / c.N2
-	cosI := c.Eyev.Dot(c.Normalv)
-	sin2T := nRatio * nRatio * (1 - cosI*cosI)
-	if sin2T > 1 {
-		// total internal refraction
+	if tr.wavelength == 0 && isDispersive(c) {
+		// white light splits into bands that each bend their own way from here on
+		for _, b := range spectralBands {
+			band := tr
+			band.wavelength = b.wavelength
+			col = col.Add(w.refractedRays(c, remaining, band).Mul(b.weight))
+		}
 		return col
 	}
+	return w.refractedRays(c, remaining, tr)
+}
 
-	cosT := math.Sqrt(1.0 - sin2T)
+// refractedRays traces the light refracted through the surface for the wavelength of the trace
+func (w *World) refractedRays(c shapes.IntersectionComputed, remaining int, tr trace) colors.Color {
+	col := colors.NewColor(0, 0, 0)
+	nRatio := refractiveRatio(c, tr.wavelength)
+
+	if c.Object.GetMaterial().Roughness > 0 {
+		col = w.glossyColor(remaining, tr, func() (geom.Ray, bool) {
+			direction, ok := roughRefractDirection(c, nRatio, tr.rng)
+			return geom.RayWith(c.UnderPoint, direction), ok
+		})
+		return col.MulBy(c.Object.GetMaterial().Transparency)
+	}
 
-	direction := c.Normalv.Mul(nRatio*cosI - cosT).Sub(c.Eyev.Mul(nRatio))
+	direction, ok := refractDirection(c, nRatio)
+	if !ok {
+		// total internal refraction
+		return col
+	}
 	refractedRay := geom.RayWith(c.UnderPoint, direction)
 
-	col = w.ColorAt(refractedRay, remaining-1).MulBy(c.Object.GetMaterial().Transparency)
+	col = w.colorAt(refractedRay, remaining-1, tr).MulBy(c.Object.GetMaterial().Transparency)
 	return col
 }
 
+// refractDirection bends the eye ray through the surface with Snell's law, false on total internal refraction
+func refractDirection(c shapes.IntersectionComputed, nRatio float64) (geom.Tuple, bool) {
+	return refract(c.Eyev, c.Normalv, nRatio)
+}
+
+// refract bends eyev through a surface with normal on the same side, for the ratio of refractive indices n1 / n2
+func refract(eyev, normal geom.Tuple, nRatio float64) (geom.Tuple, bool) {
+	cosI := eyev.Dot(normal)
+	sin2T := nRatio * nRatio * (1 - cosI*cosI)
+	if sin2T > 1 {
+		return geom.Tuple{}, false
+	}
+
+	cosT := math.Sqrt(1.0 - sin2T)
+	return normal.Mul(nRatio*cosI - cosT).Sub(eyev.Mul(nRatio)), true
+}
+
+// Divide splits groups with the bvh builder of the world and compiles the world.
+// it runs after every object was added and transformed, see Compile
 func (w *World) Divide(threshold int) {
+	w.DivideWith(threshold, w.bvhBuilder)
+}
+
+// DivideWith splits groups with the bvh builder and compiles the world with it
+func (w *World) DivideWith(threshold int, b shapes.BVHBuilder) {
+	w.bvhBuilder = b
 	for _, c := range w.objects {
-		c.Divide(threshold)
+		if g, ok := c.(shapes.Group); ok {
+			g.DivideWith(threshold, b)
+		} else {
+			c.Divide(threshold)
+		}
+	}
+	w.Compile()
+}
+
+// SetBVHBuilder picks how Divide splits groups and Compile splits the bvh, the zero value is the midpoint builder.
+// a compiled world is compiled again with it
+func (w *World) SetBVHBuilder(b shapes.BVHBuilder) {
+	w.bvhBuilder = b
+	if w.bvh != nil {
+		w.Compile()
+	}
+}
+
+// Compile flattens all objects into a bvh split with the bvh builder, that is used for intersections from then on.
+// the bvh keeps the bounds the objects had, so it must be called again after any of them is transformed or changed. Divide calls it,
+// AddObject drops it
+func (w *World) Compile() {
+	w.bvh = shapes.NewFlatBVH(w.bvhBuilder, w.objects...)
+
+	// groups may have changed since their objects were added
+	w.emitters = nil
+	w.emitterIds = map[string]bool{}
+	w.media = false
+	for _, o := range w.objects {
+		w.addEmitters(collectEmitters(o))
+		w.media = w.media || hasMedia(o)
 	}
 }
 
@@ -146,60 +307,145 @@ func (w *World) BoundsOf() *shapes.BoundingBox {
 	return b
 }
 
+// newTrace starts a whitted ray of white light, drawing its random choices from rng
+func (w *World) newTrace(rng *rand.Rand) trace {
+	return trace{glossySamples: w.glossySamples, rng: rng}
+}
+
+// seededTrace starts a whitted ray whose random choices are the same on every call
+func (w *World) seededTrace() trace {
+	return w.newTrace(rand.New(rand.NewSource(shadingSeed)))
+}
+
+// ColorAt shades the ray with Whitted style ray tracing.
+// renders go through WhittedIntegrator instead, which draws from the rng of each render worker
 func (w *World) ColorAt(r geom.Ray, remaining int) colors.Color {
+	return w.colorAt(r, remaining, w.seededTrace())
+}
+
+func (w *World) colorAt(r geom.Ray, remaining int, tr trace) colors.Color {
+	cs, ok := w.hit(r)
+	if !ok {
+		return w.throughMedium(w.fog, r, math.Inf(1), w.background(r.Direction), tr.rng)
+	}
+	col := w.shadeHit(cs, remaining, tr).Mul(transmittance(cs))
+	return w.throughMedium(w.mediumIn(cs.Medium), r, cs.Distance, col, tr.rng)
+}
+
+// closestHit finds the closest intersection in front of the ray
+func (w *World) closestHit(r geom.Ray) (shapes.Intersection, bool) {
+	if w.bvh != nil {
+		return w.bvh.ClosestHit(r)
+	}
+	return w.Intersect(r).Hit()
+}
+
+// hit finds and prepares the closest visible intersection along the ray
+func (w *World) hit(r geom.Ray) (shapes.IntersectionComputed, bool) {
+	if w.bvh != nil {
+		i, ok := w.bvh.ClosestHit(r)
+		if !ok {
+			return shapes.IntersectionComputed{}, false
+		}
+		// every intersection along the ray is only needed to find refractive indices and the medium
+		if i.O.GetMaterial().Transparency == 0 && !w.media && !materials.IsParticipating(w.fog) {
+			return i.Compute(r, shapes.NewIntersections(i)), true
+		}
+	}
+
 	is := w.Intersect(r)
 	i, ok := is.Hit()
 	if !ok {
-		return colors.Black()
+		return shapes.IntersectionComputed{}, false
 	}
-
-	cs := i.Compute(r, is)
-	return w.ShadeHit(cs, remaining)
+	return i.Compute(r, is), true
 }
 
 func (w *World) IsShadowed(lightPosition geom.Tuple, p geom.Tuple) bool {
 	v := lightPosition.Sub(p)
-	distance := v.Mag()
-	direction := v.Normalize()
+	return w.isOccluded(p, v.Normalize(), v.Mag())
+}
 
+// isOccluded is true when something is within distance of p along direction
+func (w *World) isOccluded(p geom.Tuple, direction geom.Tuple, distance float64) bool {
 	r := geom.RayWith(p, direction)
-	intersections := w.Intersect(r)
-	h, ok := intersections.Hit()
 	// shadowless object does not cast shadows onto other objects
-	if ok && h.T < distance && !h.O.GetShadowless() {
-		return true
+	if w.bvh != nil {
+		return w.bvh.AnyHit(r, distance)
+	}
+	for _, s := range w.objects {
+		if s.AnyHit(r, distance) {
+			return true
+		}
 	}
 	return false
 }
 
-func IntensityAt(p shapes.PointLight, pt geom.Tuple, w *World) float64 {
-	v := w.IsShadowed(p.Position, pt)
-	if v {
+// IntensityAt is the fraction of the light samples that reach pt without a shadow
+func IntensityAt(l shapes.Light, pt geom.Tuple, w *World) float64 {
+	samples := l.SamplesFrom(pt)
+	if len(samples) == 0 {
 		return 0.0
 	}
-	return 1.0
-}
 
-func IntensityAtAreaLight(l shapes.AreaLight, pt geom.Tuple, w *World) float64 {
 	total := 0.0
-
-	for v := 0; v < l.VSteps; v++ {
-		for u := 0; u < l.USteps; u++ {
-			lightPosition := l.PointOnLight(u, v)
-			if !w.IsShadowed(lightPosition, pt) {
-				total += 1.0
-			}
+	for _, s := range samples {
+		if !w.isOccluded(pt, s.Direction, s.Distance) {
+			total += 1.0
 		}
 	}
-	return total / float64(l.Samples)
+	return total / float64(len(samples))
 }
 
-// todo replace c.rencder?
-// or wrap this with an option to consume all and then return the image and delete c.render
-
 func Render(ctx context.Context, w *World, c Camera, rayBounces int, numGoRoutines int, renderMode coordinate_supplier.Order) (<-chan PixelInfo, error) {
 	pi := make(chan PixelInfo, numGoRoutines*2)
 
+	cs, err := newCoordinateSupplier(c, renderMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// adaptive sampling needs every pixel center before it can compare neighbours
+	var centers *pixelCenters
+	var firstPass coordinate_supplier.CoordinateSupplier
+	if c.Sampling.Pattern == SampleAdaptive {
+		centers = newPixelCenters(c.HSize, c.VSize)
+		firstPass, err = newCoordinateSupplier(c, coordinate_supplier.Asc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	go func() {
+		defer close(pi)
+		if centers != nil {
+			renderPixels(ctx, firstPass, numGoRoutines, c.Sampling.Seed, func(x, y int, rng *rand.Rand) {
+				centers.set(x, y, c.colorAt(w, x, y, 0.5, 0.5, rayBounces, rng))
+			})
+		}
+
+		renderPixels(ctx, cs, numGoRoutines, c.Sampling.Seed, func(x, y int, rng *rand.Rand) {
+			var col colors.Color
+			var samples int
+			if centers != nil {
+				col, samples = c.adaptiveColorForPixel(w, centers, x, y, rayBounces, rng)
+			} else {
+				col, samples = c.colorForPixel(w, x, y, rayBounces, rng)
+			}
+
+			pi <- PixelInfo{
+				X:       x,
+				Y:       y,
+				C:       col,
+				Samples: samples,
+			}
+		})
+	}()
+
+	return pi, nil
+}
+
+func newCoordinateSupplier(c Camera, renderMode coordinate_supplier.Order) (coordinate_supplier.CoordinateSupplier, error) {
 	cs, err := coordinate_supplier.NewCoordinateSupplierAtomic(coordinate_supplier.CoordinateSupplierOptions{
 		Width:  c.HSize,
 		Height: c.VSize,
@@ -210,36 +456,33 @@ func Render(ctx context.Context, w *World, c Camera, rayBounces int, numGoRoutin
 	if err != nil {
 		return nil, fmt.Errorf("failed create coordinate supplier: %w", err)
 	}
+	return cs, nil
+}
 
-	go func() {
-		wg := sync.WaitGroup{}
-		for i := 0; i < numGoRoutines; i++ {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				for x, y, _, done := cs.Next(); !done; x, y, _, done = cs.Next() {
-					select {
-					case <-ctx.Done():
-						// cancel render goroutine
-						return
-					default:
-						// noop
-					}
-
-					r := c.rayForPixel(x, y)
-					c := w.ColorAt(r, rayBounces)
-
-					pi <- PixelInfo{
-						X: x,
-						Y: y,
-						C: c,
-					}
+// renderPixels calls f for every coordinate across numGoRoutines workers and waits for them to finish
+func renderPixels(ctx context.Context, cs coordinate_supplier.CoordinateSupplier, numGoRoutines int, seed int64, f func(x, y int, rng *rand.Rand)) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < numGoRoutines; i++ {
+		wg.Add(1)
+		// math/rand's global source is locked, so each worker gets its own
+		rng := rand.New(rand.NewSource(seed + int64(i)))
+		go func() {
+			defer wg.Done()
+			for x, y, _, done := cs.Next(); !done; x, y, _, done = cs.Next() {
+				select {
+				case <-ctx.Done():
+					// cancel render goroutine
+					return
+				default:
+					// noop
 				}
-			}()
-		}
-		wg.Wait()
-		close(pi)
-	}()
 
-	return pi, nil
+				f(x, y, rng)
+			}
+		}()
+	}
+	wg.Wait()
 }
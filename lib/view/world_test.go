@@ -17,7 +17,7 @@ func Test_NewWorld(t *testing.T) {
 	w := NewWorld()
 
 	assert.Len(t, w.objects, 0)
-	assert.Len(t, w.pointLights, 0)
+	assert.Len(t, w.lights, 0)
 }
 
 func Test_DefaultWorld(t *testing.T) {
@@ -35,8 +35,8 @@ func Test_DefaultWorld(t *testing.T) {
 	assert.Len(t, w.objects, 2)
 	assert.Equal(t, w.objects[0].GetMaterial(), sA.GetMaterial())
 	assert.Equal(t, w.objects[1].GetMaterial(), sB.GetMaterial())
-	assert.Len(t, w.pointLights, 1)
-	assert.Contains(t, w.pointLights, l)
+	assert.Len(t, w.lights, 1)
+	assert.Contains(t, w.lights, l)
 }
 
 func Test_World_Ray_Intersect(t *testing.T) {
@@ -52,6 +52,27 @@ func Test_World_Ray_Intersect(t *testing.T) {
 	assert.Equal(t, 6.0, xs.I[3].T)
 }
 
+func Test_CompiledWorld_MatchesUncompiled(t *testing.T) {
+	rays := []geom.Ray{
+		geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1)),
+		geom.RayWith(geom.NewPoint(0, 0, 0.75), geom.NewVector(0, 0, -1)),
+		geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 1, 0)),
+		geom.RayWith(geom.NewPoint(0.3, 0.2, -5), geom.NewVector(0, 0, 1)),
+	}
+
+	for ti, r := range rays {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			w := defaultWorld()
+			compiled := defaultWorld()
+			compiled.objects = w.objects
+			compiled.Compile()
+
+			assert.Equal(t, w.Intersect(r), compiled.Intersect(r))
+			assert.Equal(t, w.ColorAt(r, 3), compiled.ColorAt(r, 3))
+		})
+	}
+}
+
 func Test_Shading_Intersection(t *testing.T) {
 	w := defaultWorld()
 	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
@@ -66,7 +87,7 @@ func Test_Shading_Intersection(t *testing.T) {
 
 func Test_Shading_Intersection_Inside(t *testing.T) {
 	w := defaultWorld()
-	w.pointLights[0] = shapes.NewPointLight(geom.NewPoint(0, 0.25, 0), colors.White())
+	w.lights[0] = shapes.NewPointLight(geom.NewPoint(0, 0.25, 0), colors.White())
 	r := geom.RayWith(geom.NewPoint(0, 0, 0), geom.NewVector(0, 0, 1))
 	s := w.objects[1]
 
@@ -135,6 +156,40 @@ func Test_IsShadowed(t *testing.T) {
 	}
 }
 
+func Test_IsShadowed_BehindShadowless(t *testing.T) {
+	w := NewWorld()
+	var s1 shapes.Shape = shapes.NewSphere()
+	s1.SetShadowless(true)
+	w.AddObject(s1)
+	s2 := shapes.NewSphere()
+	s2.SetTransform(geom.Translate(0, 0, 3))
+	w.AddObject(s2)
+	lightPosition := geom.NewPoint(0, 0, 10)
+	p := geom.NewPoint(0, 0, -5)
+
+	// the shadowless sphere is hit first but the one behind it still casts a shadow
+	require.True(t, w.IsShadowed(lightPosition, p))
+	w.Compile()
+	require.True(t, w.IsShadowed(lightPosition, p))
+
+	s2.SetShadowless(true)
+	require.False(t, w.IsShadowed(lightPosition, p))
+}
+
+func Test_AddObject_AfterCompile(t *testing.T) {
+	w := NewWorld()
+	w.AddObject(shapes.NewSphere())
+	w.Compile()
+	r := geom.RayWith(geom.NewPoint(0, 0, -5), geom.NewVector(0, 0, 1))
+	require.Len(t, w.Intersect(r).I, 2)
+
+	// the compiled bvh is dropped, so the new sphere is hit too
+	s := shapes.NewSphere()
+	s.SetTransform(geom.Translate(0, 0, 3))
+	w.AddObject(s)
+	require.Len(t, w.Intersect(r).I, 4)
+}
+
 func Test_ShadeHit_HasShadow(t *testing.T) {
 	w := NewWorld()
 	w.AddPointLight(shapes.NewPointLight(geom.NewPoint(0, 0, -10), colors.White()))
@@ -428,8 +483,8 @@ func Test_UnShaded_NotShadedByOthers(t *testing.T) {
 
 func Test_PointLight_PassesIntensity(t *testing.T) {
 	w := defaultWorld()
-	require.Len(t, w.pointLights, 1)
-	l := w.pointLights[0]
+	require.Len(t, w.lights, 1)
+	l := w.lights[0]
 
 	type args struct {
 		p      geom.Tuple
@@ -477,7 +532,7 @@ func Test_AreaLight_PassesIntensity(t *testing.T) {
 
 	for ti, tt := range tests {
 		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
-			intensity := IntensityAtAreaLight(light, tt.p, w)
+			intensity := IntensityAt(light, tt.p, w)
 			require.Equal(t, tt.expect, intensity)
 		})
 	}
@@ -506,7 +561,7 @@ func Test_AreaLightJittered_PassesIntensity(t *testing.T) {
 	for ti, tt := range tests {
 		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
 			light := shapes.NewAreaLight(corner, v1, 2, v2, 2, colors.White(), shapes.NewJitterSequence(0.7, 0.3, 0.9, 0.1, 0.5))
-			intensity := IntensityAtAreaLight(light, tt.p, w)
+			intensity := IntensityAt(light, tt.p, w)
 			require.Equal(t, tt.expect, intensity)
 		})
 	}
@@ -542,8 +597,8 @@ func Test_AreaLight_JitteredPoints(t *testing.T) {
 
 func Test_Lighting_UsesIntensity(t *testing.T) {
 	w := defaultWorld()
-	require.Len(t, w.pointLights, 1)
-	w.pointLights[0] = shapes.PointLight{
+	require.Len(t, w.lights, 1)
+	w.lights[0] = shapes.PointLight{
 		Position:  geom.NewPoint(0, 0, -10),
 		Intensity: colors.NewColor(1, 1, 1),
 	}
@@ -573,9 +628,67 @@ func Test_Lighting_UsesIntensity(t *testing.T) {
 
 	for ti, tt := range tests {
 		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
-			c := shapes.Lighting(s.GetMaterial(), s, w.pointLights[0], pt, eyeV, normalV, tt.intensity)
+			c := shapes.Lighting(s.GetMaterial(), s, w.lights[0], pt, eyeV, normalV, tt.intensity)
 			require.Equal(t, tt.expect, c)
 		})
 	}
 
 }
+
+func Test_World_Lights_Unified(t *testing.T) {
+	w := NewWorld()
+	w.AddPointLight(shapes.NewPointLight(geom.NewPoint(0, 10, 0), colors.White()))
+	w.AddAreaLight(shapes.NewAreaLight(geom.NewPoint(0, 10, 0), geom.NewVector(1, 0, 0), 2, geom.NewVector(0, 0, 1), 2, colors.White(), nil))
+	w.AddLight(shapes.NewSpotLight(geom.NewPoint(0, 10, 0), geom.ZeroPoint(), 0.2, 0.3, colors.White()))
+	w.AddLight(shapes.NewDirectionalLight(geom.NewVector(0, -1, 0), 0, 1, colors.White(), nil))
+
+	require.Len(t, w.lights, 4)
+}
+
+func Test_SpotLight_LightsInsideCone(t *testing.T) {
+	w := NewWorld()
+	floor := shapes.NewPlane()
+	m := floor.GetMaterial()
+	m.Ambient = 0
+	m.Specular = 0
+	floor.SetMaterial(m)
+	w.AddObject(floor)
+	w.AddLight(shapes.NewSpotLight(geom.NewPoint(0, 10, 0), geom.ZeroPoint(), math.Pi/16, math.Pi/8, colors.White()))
+
+	down := geom.NewVector(0, -1, 0)
+	inside := w.ColorAt(geom.RayWith(geom.NewPoint(0, 1, 0), down), 0)
+	outside := w.ColorAt(geom.RayWith(geom.NewPoint(5, 1, 0), down), 0)
+
+	require.InDelta(t, 0.9, inside.R, 1e-4)
+	require.Equal(t, colors.Black(), outside)
+}
+
+func Test_DirectionalLight_PassesIntensity(t *testing.T) {
+	w := defaultWorld()
+	hard := shapes.NewDirectionalLight(geom.NewVector(0, -1, 0), 0, 1, colors.White(), nil)
+	// a sun 90 degrees wide, right below the sphere it is hidden completely and further down only partly
+	soft := shapes.NewDirectionalLight(geom.NewVector(0, -1, 0), math.Pi/2, 64, colors.White(), shapes.NewJitterSequence(0.1, 0.3, 0.5, 0.7, 0.9, 0.2, 0.4, 0.6, 0.8))
+
+	type args struct {
+		l      shapes.Light
+		p      geom.Tuple
+		expect float64
+	}
+
+	tests := []args{
+		{hard, geom.NewPoint(0, 5, 0), 1},
+		{hard, geom.NewPoint(0, -5, 0), 0},
+		{hard, geom.NewPoint(5, -5, 0), 1},
+		{soft, geom.NewPoint(0, -1.01, 0), 0},
+		{soft, geom.NewPoint(100, -5, 0), 1},
+	}
+
+	for ti, tt := range tests {
+		t.Run(t.Name()+strconv.Itoa(ti), func(t *testing.T) {
+			require.Equal(t, tt.expect, IntensityAt(tt.l, tt.p, w))
+		})
+	}
+
+	partial := IntensityAt(soft, geom.NewPoint(0, -3, 0), w)
+	require.True(t, partial > 0 && partial < 1)
+}